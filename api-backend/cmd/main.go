@@ -13,8 +13,11 @@ import (
 	"adcms/internal/router"
 	"adcms/pkg/crontab"
 	"adcms/pkg/database"
+	"adcms/pkg/iplocate"
+	"adcms/pkg/jwtkeys"
 	"adcms/pkg/logcfg"
 	"adcms/pkg/logger"
+	"adcms/pkg/passkey"
 	"adcms/pkg/storage"
 	"fmt"
 	"os"
@@ -57,6 +60,31 @@ func main() {
 	}
 	defer database.CloseRedis()
 
+	// 初始化JWT签名密钥（RS256/EdDSA）
+	if err := jwtkeys.Setup(); err != nil {
+		logger.Fatalf("Failed to init JWT keys: %v", err)
+	}
+
+	// 命令行轮换签名密钥: ./adcms config.yaml rotate-jwt-key
+	if len(os.Args) > 2 && os.Args[2] == "rotate-jwt-key" {
+		key, err := jwtkeys.Rotate()
+		if err != nil {
+			logger.Fatalf("Failed to rotate JWT key: %v", err)
+		}
+		fmt.Printf("JWT signing key rotated: %s (%s)\n", key.Kid, key.Algorithm)
+		return
+	}
+
+	// 加载离线 IP 归属地数据
+	if err := iplocate.Setup(cfg.IPRegion.Path); err != nil {
+		logger.Warnf("Failed to load IP region data: %v", err)
+	}
+
+	// 初始化通行密钥（WebAuthn）
+	if err := passkey.Setup(); err != nil {
+		logger.Fatalf("Failed to init WebAuthn: %v", err)
+	}
+
 	// 初始化文件存储
 	initStorage(&cfg.Storage)
 
@@ -0,0 +1,69 @@
+package authn
+
+import (
+	"adcms/internal/model"
+	"adcms/internal/repository"
+	"adcms/pkg/utils"
+	"errors"
+)
+
+// 用户名密码登录的认证方式，按顺序尝试：
+//   - 返回 ErrUserNotFound 表示该方式不负责此用户，交给下一个认证方式
+//   - 其他错误直接作为认证结果返回，不再尝试后续方式
+var (
+	ErrUserNotFound    = errors.New("用户不存在")
+	ErrInvalidPassword = errors.New("密码错误")
+	ErrAccountDisabled = errors.New("目录账号已停用") // 未校验密码，不能当作认证通过
+)
+
+// Credentials 登录提交的凭据
+type Credentials struct {
+	Username string
+	Password string
+	TenantID uint // 登录页所属租户，目录用户首次登录自动创建账号时使用，可为 0
+}
+
+// Authenticator 认证方式
+// 返回 ErrInvalidPassword、ErrAccountDisabled 时可同时返回用户，用于记录登录日志
+type Authenticator interface {
+	Authenticate(cred Credentials) (*model.User, error)
+}
+
+// Chain 依次尝试多个认证方式
+type Chain []Authenticator
+
+func (c Chain) Authenticate(cred Credentials) (*model.User, error) {
+	for _, a := range c {
+		user, err := a.Authenticate(cred)
+		if errors.Is(err, ErrUserNotFound) {
+			continue
+		}
+		return user, err
+	}
+	return nil, ErrUserNotFound
+}
+
+// Default 登录使用的认证链：目录账号优先，其余按本地密码校验
+func Default() Authenticator {
+	return Chain{NewLDAP(), NewLocal()}
+}
+
+// Local 本地密码认证
+type Local struct {
+	userRepo *repository.UserRepository
+}
+
+func NewLocal() *Local {
+	return &Local{userRepo: repository.NewUserRepository()}
+}
+
+func (a *Local) Authenticate(cred Credentials) (*model.User, error) {
+	user, err := a.userRepo.FindByUsernameGlobal(cred.Username)
+	if err != nil {
+		return nil, ErrUserNotFound
+	}
+	if !utils.ComparePassword(user.Password, cred.Password) {
+		return user, ErrInvalidPassword
+	}
+	return user, nil
+}
@@ -0,0 +1,202 @@
+package authn
+
+import (
+	"adcms/internal/middleware"
+	"adcms/internal/model"
+	"adcms/internal/repository"
+	"adcms/pkg/ldapauth"
+	"adcms/pkg/session"
+	"adcms/pkg/utils"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"sort"
+)
+
+// LDAP 目录认证
+//   - 已绑定目录身份的用户始终通过目录校验密码，登录成功后按分组同步角色
+//   - 未绑定的本地用户返回 ErrUserNotFound，交给本地密码认证
+//   - 本地不存在的用户名，在登录页所属租户开启了自动创建的目录中校验通过后自动创建
+type LDAP struct {
+	userRepo *repository.UserRepository
+	ldapRepo *repository.LDAPRepository
+}
+
+func NewLDAP() *LDAP {
+	return &LDAP{
+		userRepo: repository.NewUserRepository(),
+		ldapRepo: repository.NewLDAPRepository(),
+	}
+}
+
+func (a *LDAP) Authenticate(cred Credentials) (*model.User, error) {
+	// 按租户目录身份查找（自动创建时可能因重名使用了不同的本地用户名）
+	if cred.TenantID > 0 {
+		configs, err := a.ldapRepo.ListEnabled(cred.TenantID)
+		if err != nil {
+			return nil, err
+		}
+		for i := range configs {
+			if ident, err := a.ldapRepo.FindIdentity(configs[i].ID, cred.Username); err == nil {
+				user, err := a.userRepo.FindByID(ident.UserID)
+				if err != nil || user.TenantID != configs[i].TenantID {
+					return nil, ErrUserNotFound
+				}
+				return a.verify(user, &configs[i], ident, cred.Password)
+			}
+		}
+	}
+
+	user, err := a.userRepo.FindByUsernameGlobal(cred.Username)
+	if err == nil {
+		ident, err := a.ldapRepo.FindIdentityByUser(user.ID)
+		if err != nil {
+			return nil, ErrUserNotFound
+		}
+		cfg, err := a.ldapRepo.FindByID(ident.ProviderID)
+		// 目录配置已停用或删除时按本地账号处理
+		if err != nil || cfg.Status != 1 || cfg.TenantID != user.TenantID {
+			return nil, ErrUserNotFound
+		}
+		return a.verify(user, cfg, ident, cred.Password)
+	}
+
+	if cred.TenantID == 0 {
+		return nil, ErrUserNotFound
+	}
+	return a.provision(cred)
+}
+
+// verify 通过目录校验已绑定用户的密码
+func (a *LDAP) verify(user *model.User, cfg *model.LDAPConfig, ident *model.UserIdentity, password string) (*model.User, error) {
+	entry, err := ldapauth.Authenticate(cfg, ident.Subject, password)
+	switch {
+	case errors.Is(err, ldapauth.ErrInvalidCredentials), errors.Is(err, ldapauth.ErrUserNotFound):
+		return user, ErrInvalidPassword
+	case errors.Is(err, ldapauth.ErrAccountDisabled):
+		// 目录中已停用，同步禁用本地账号；密码未经校验，按认证失败返回
+		if user.Status == 1 {
+			a.disable(user.ID)
+			user.Status = 0
+		}
+		return user, ErrAccountDisabled
+	case err != nil:
+		return nil, err
+	}
+
+	a.ldapRepo.TouchIdentity(ident.ID)
+	if user.IsAdmin == 0 {
+		a.syncRoles(user.ID, ldapauth.MapRoles(cfg, entry.Groups))
+	}
+	return user, nil
+}
+
+// syncRoles 目录分组映射出角色时，以映射结果覆盖用户角色
+func (a *LDAP) syncRoles(userID uint, roleIDs []uint) {
+	if len(roleIDs) == 0 {
+		return
+	}
+	roles, err := a.userRepo.GetUserRoles(userID)
+	if err != nil {
+		return
+	}
+	current := make([]uint, len(roles))
+	for i, r := range roles {
+		current[i] = r.ID
+	}
+	if sameIDs(current, roleIDs) {
+		return
+	}
+	if err := a.userRepo.AssignRoles(userID, roleIDs); err != nil {
+		fmt.Printf("[LDAP] 同步用户%d角色失败: %v\n", userID, err)
+		return
+	}
+	middleware.ClearUserPermissionCache(userID)
+}
+
+// provision 在租户开启自动创建的目录中校验，通过后创建本地用户并绑定
+func (a *LDAP) provision(cred Credentials) (*model.User, error) {
+	configs, err := a.ldapRepo.ListEnabled(cred.TenantID)
+	if err != nil {
+		return nil, err
+	}
+	for i := range configs {
+		cfg := &configs[i]
+		if cfg.AutoCreate != 1 {
+			continue
+		}
+		entry, err := ldapauth.Authenticate(cfg, cred.Username, cred.Password)
+		if errors.Is(err, ldapauth.ErrUserNotFound) {
+			continue
+		}
+		if errors.Is(err, ldapauth.ErrInvalidCredentials) || errors.Is(err, ldapauth.ErrAccountDisabled) {
+			return nil, ErrInvalidPassword
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		password, err := randomPassword()
+		if err != nil {
+			return nil, err
+		}
+		user := &model.User{
+			TenantBaseModel: model.TenantBaseModel{TenantID: cfg.TenantID},
+			Username:        cred.Username,
+			Password:        password,
+			Email:           truncate(entry.Email, 100),
+			Phone:           truncate(entry.Phone, 20),
+			Nickname:        truncate(entry.Nickname, 50),
+			Status:          1,
+			Remark:          "目录账号自动创建：" + cfg.Name,
+		}
+		ident := &model.UserIdentity{ProviderID: cfg.ID, Subject: cred.Username}
+		if err := a.ldapRepo.Provision(user, ldapauth.MapRoles(cfg, entry.Groups), ident); err != nil {
+			return nil, errors.New("创建用户失败")
+		}
+		return user, nil
+	}
+	return nil, ErrUserNotFound
+}
+
+// disable 禁用目录中已停用的账号，并使其现有会话和 token 失效
+func (a *LDAP) disable(userID uint) error {
+	if err := a.userRepo.UpdateStatus(userID, 0); err != nil {
+		return err
+	}
+	session.RevokeUser(userID, "")
+	return session.BumpVersion(userID)
+}
+
+// randomPassword 目录用户不使用本地密码，设置随机密码
+func randomPassword() (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return utils.HashPassword(hex.EncodeToString(buf))
+}
+
+func truncate(s string, n int) string {
+	if r := []rune(s); len(r) > n {
+		return string(r[:n])
+	}
+	return s
+}
+
+func sameIDs(a, b []uint) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	a = append([]uint(nil), a...)
+	b = append([]uint(nil), b...)
+	sort.Slice(a, func(i, j int) bool { return a[i] < a[j] })
+	sort.Slice(b, func(i, j int) bool { return b[i] < b[j] })
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -7,17 +7,20 @@ import (
 )
 
 type Config struct {
-	Server  ServerConfig  `mapstructure:"server"`
-	MySQL   MySQLConfig   `mapstructure:"mysql"`
-	Redis   RedisConfig   `mapstructure:"redis"`
-	JWT     JWTConfig     `mapstructure:"jwt"`
-	Log     LogConfig     `mapstructure:"log"`
-	Storage StorageConfig `mapstructure:"storage"`
+	Server   ServerConfig   `mapstructure:"server"`
+	MySQL    MySQLConfig    `mapstructure:"mysql"`
+	Redis    RedisConfig    `mapstructure:"redis"`
+	JWT      JWTConfig      `mapstructure:"jwt"`
+	Log      LogConfig      `mapstructure:"log"`
+	Storage  StorageConfig  `mapstructure:"storage"`
+	WebAuthn WebAuthnConfig `mapstructure:"webauthn"`
+	IPRegion IPRegionConfig `mapstructure:"ip_region"`
 }
 
 type ServerConfig struct {
-	Port int    `mapstructure:"port"`
-	Mode string `mapstructure:"mode"`
+	Port      int    `mapstructure:"port"`
+	Mode      string `mapstructure:"mode"`
+	PublicURL string `mapstructure:"public_url"` // 对外访问地址，用于邮件中的链接，为空时登录提醒邮件不附带链接
 }
 
 type MySQLConfig struct {
@@ -48,9 +51,23 @@ func (r *RedisConfig) Addr() string {
 }
 
 type JWTConfig struct {
-	Secret       string `mapstructure:"secret"`
-	ExpireHours  int    `mapstructure:"expire_hours"`
-	RefreshHours int    `mapstructure:"refresh_hours"`
+	Secret        string `mapstructure:"secret"`
+	Algorithm     string `mapstructure:"algorithm"` // HS256（默认，使用 secret）、RS256、EdDSA
+	ExpireHours   int    `mapstructure:"expire_hours"`
+	AccessMinutes int    `mapstructure:"access_minutes"` // 短期 access token 有效期（分钟），0=使用 expire_hours
+	RefreshHours  int    `mapstructure:"refresh_hours"`  // refresh token 有效期（小时）
+}
+
+// WebAuthnConfig 通行密钥（WebAuthn）配置，rp_id 为空时不启用
+type WebAuthnConfig struct {
+	RPID    string   `mapstructure:"rp_id"`   // 站点域名，如 admin.example.com
+	RPName  string   `mapstructure:"rp_name"` // 展示名称
+	Origins []string `mapstructure:"origins"` // 允许的前端来源，如 https://admin.example.com
+}
+
+// IPRegionConfig 离线 IP 归属地数据，每行 "起始IP|结束IP|行政区划代码"
+type IPRegionConfig struct {
+	Path string `mapstructure:"path"` // 为空时仅识别内网地址
 }
 
 type LogConfig struct {
@@ -4,6 +4,7 @@ import (
 	"adcms/internal/middleware"
 	"adcms/internal/model"
 	"adcms/internal/repository"
+	"adcms/pkg/session"
 	"adcms/pkg/utils"
 	"strconv"
 	"time"
@@ -12,14 +13,16 @@ import (
 )
 
 type AdminHandler struct {
-	adminRepo *repository.AdminRepository
-	userRepo  *repository.UserRepository
+	adminRepo  *repository.AdminRepository
+	userRepo   *repository.UserRepository
+	apiKeyRepo *repository.APIKeyRepository
 }
 
 func NewAdminHandler() *AdminHandler {
 	return &AdminHandler{
-		adminRepo: repository.NewAdminRepository(),
-		userRepo:  repository.NewUserRepository(),
+		adminRepo:  repository.NewAdminRepository(),
+		userRepo:   repository.NewUserRepository(),
+		apiKeyRepo: repository.NewAPIKeyRepository(),
 	}
 }
 
@@ -38,6 +41,9 @@ type CreateAdminRequest struct {
 }
 
 func (h *AdminHandler) Create(c *gin.Context) {
+	// 请求中含明文密码，不写入操作日志
+	middleware.OmitRequestLog(c)
+
 	// 只有超管可以创建管理员
 	if !middleware.IsSuperAdmin(middleware.GetUserID(c)) {
 		utils.Forbidden(c, "无权操作")
@@ -56,6 +62,9 @@ func (h *AdminHandler) Create(c *gin.Context) {
 		utils.Fail(c, 3001, "用户名已存在")
 		return
 	}
+	if !checkNewPassword(c, h.userRepo, &model.User{Username: req.Username}, req.Password) {
+		return
+	}
 
 	hashedPassword, err := utils.HashPassword(req.Password)
 	if err != nil {
@@ -81,6 +90,7 @@ func (h *AdminHandler) Create(c *gin.Context) {
 		LoginCount:      0,
 		LastLoginAt:     &now,
 	}
+	user.PasswordChangedAt = &now
 
 	if err := h.userRepo.Create(&user); err != nil {
 		utils.ServerError(c, "创建管理员失败")
@@ -150,6 +160,7 @@ func (h *AdminHandler) Update(c *gin.Context) {
 	user.Domain = req.Domain
 	user.ExpireTime = req.ExpireTime
 	user.MaxUsers = req.MaxUsers
+	statusChanged := user.Status != req.Status
 	user.Remark = req.Remark
 	user.Status = req.Status
 
@@ -157,6 +168,12 @@ func (h *AdminHandler) Update(c *gin.Context) {
 		utils.ServerError(c, "更新失败")
 		return
 	}
+	if statusChanged {
+		session.BumpVersion(user.ID)
+		if user.Status != 1 {
+			session.RevokeUser(user.ID, "")
+		}
+	}
 
 	utils.Success(c, user)
 }
@@ -192,6 +209,8 @@ func (h *AdminHandler) Delete(c *gin.Context) {
 		utils.ServerError(c, "删除失败")
 		return
 	}
+	session.RevokeUser(user.ID, "")
+	session.BumpVersion(user.ID)
 
 	utils.SuccessWithMessage(c, "删除成功", nil)
 }
@@ -273,11 +292,18 @@ func (h *AdminHandler) ToggleStatus(c *gin.Context) {
 		utils.ServerError(c, "更新失败")
 		return
 	}
+	session.BumpVersion(user.ID)
+	if user.Status != 1 {
+		session.RevokeUser(user.ID, "")
+	}
 
 	utils.SuccessWithMessage(c, "状态更新成功", user)
 }
 
 func (h *AdminHandler) ResetPassword(c *gin.Context) {
+	// 请求中含明文密码，不写入操作日志
+	middleware.OmitRequestLog(c)
+
 	// 只有超管可以重置管理员密码
 	if !middleware.IsSuperAdmin(middleware.GetUserID(c)) {
 		utils.Forbidden(c, "无权操作")
@@ -308,6 +334,9 @@ func (h *AdminHandler) ResetPassword(c *gin.Context) {
 		utils.Fail(c, 4003, "该用户不是管理员")
 		return
 	}
+	if !checkNewPassword(c, h.userRepo, user, req.Password) {
+		return
+	}
 
 	hashedPassword, err := utils.HashPassword(req.Password)
 	if err != nil {
@@ -315,11 +344,14 @@ func (h *AdminHandler) ResetPassword(c *gin.Context) {
 		return
 	}
 
-	user.Password = hashedPassword
-	if err := h.userRepo.Update(user); err != nil {
+	// 管理员下次登录须先修改密码
+	if err := h.userRepo.ResetPassword(user.ID, hashedPassword); err != nil {
 		utils.ServerError(c, "更新失败")
 		return
 	}
+	session.RevokeUser(user.ID, "")
+	session.BumpVersion(user.ID)
+	h.apiKeyRepo.DeleteByUser(user.ID)
 
 	utils.SuccessWithMessage(c, "密码重置成功", nil)
 }
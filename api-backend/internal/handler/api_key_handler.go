@@ -0,0 +1,117 @@
+package handler
+
+import (
+	"adcms/internal/middleware"
+	"adcms/internal/model"
+	"adcms/pkg/utils"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// maxAPIKeysPerUser 每个用户最多可创建的 API Key 数量
+const maxAPIKeysPerUser = 20
+
+// APIKeys 当前用户的 API Key 列表（不含密钥）
+func (h *AuthHandler) APIKeys(c *gin.Context) {
+	keys, err := h.apiKeyRepo.ListByUser(middleware.GetUserID(c))
+	if err != nil {
+		utils.ServerError(c, "查询失败")
+		return
+	}
+	utils.Success(c, keys)
+}
+
+type CreateAPIKeyRequest struct {
+	Name          string   `json:"name" binding:"required,max=100"`
+	Scopes        []string `json:"scopes" binding:"required,min=1"`          // 权限码，只能是自己拥有的
+	ExpiresInDays int      `json:"expires_in_days" binding:"min=0,max=3650"` // 0 表示永不过期
+}
+
+// CreateAPIKey 创建 API Key，完整密钥只在本次返回
+func (h *AuthHandler) CreateAPIKey(c *gin.Context) {
+	var req CreateAPIKeyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.BadRequest(c, "参数错误")
+		return
+	}
+
+	userID := middleware.GetUserID(c)
+	if count, err := h.apiKeyRepo.CountByUser(userID); err != nil || count >= maxAPIKeysPerUser {
+		utils.BadRequest(c, "API Key 数量已达上限，请先删除不再使用的")
+		return
+	}
+
+	// 授权范围不能超出用户自己的权限
+	owned := make(map[string]bool)
+	for _, code := range middleware.GetUserPermissionCodes(userID) {
+		owned[code] = true
+	}
+	seen := make(map[string]bool)
+	var scopes []string
+	for _, code := range req.Scopes {
+		code = strings.TrimSpace(code)
+		if code == "" || seen[code] {
+			continue
+		}
+		if !owned[code] {
+			utils.Fail(c, 4003, "无权授予权限: "+code)
+			return
+		}
+		seen[code] = true
+		scopes = append(scopes, code)
+	}
+	if len(scopes) == 0 {
+		utils.BadRequest(c, "请选择授权范围")
+		return
+	}
+
+	key, prefix, secretHash, err := utils.GenerateAPIKey()
+	if err != nil {
+		utils.ServerError(c, "生成 API Key 失败")
+		return
+	}
+	record := model.APIKey{
+		UserID:     userID,
+		TenantID:   middleware.GetTenantID(c),
+		Name:       req.Name,
+		Prefix:     prefix,
+		SecretHash: secretHash,
+		Scopes:     strings.Join(scopes, ","),
+	}
+	if req.ExpiresInDays > 0 {
+		expiresAt := time.Now().AddDate(0, 0, req.ExpiresInDays)
+		record.ExpiresAt = &expiresAt
+	}
+	if err := h.apiKeyRepo.Create(&record); err != nil {
+		utils.ServerError(c, "创建失败")
+		return
+	}
+
+	middleware.OmitResponseLog(c)
+	utils.SuccessWithMessage(c, "创建成功，请立即保存密钥，关闭后将无法再次查看", gin.H{
+		"key":     key,
+		"api_key": record,
+	})
+}
+
+// DeleteAPIKey 删除（吊销）API Key
+func (h *AuthHandler) DeleteAPIKey(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		utils.BadRequest(c, "参数错误")
+		return
+	}
+	deleted, err := h.apiKeyRepo.Delete(uint(id), middleware.GetUserID(c))
+	if err != nil {
+		utils.ServerError(c, "删除失败")
+		return
+	}
+	if !deleted {
+		utils.Fail(c, 404, "API Key 不存在")
+		return
+	}
+	utils.SuccessWithMessage(c, "删除成功", nil)
+}
@@ -1,43 +1,75 @@
 package handler
 
 import (
+	"adcms/internal/authn"
 	"adcms/internal/middleware"
 	"adcms/internal/model"
 	"adcms/internal/repository"
+	"adcms/pkg/captcha"
 	"adcms/pkg/database"
 	"adcms/pkg/email"
+	"adcms/pkg/iplocate"
 	"adcms/pkg/logcfg"
+	"adcms/pkg/passkey"
+	"adcms/pkg/session"
 	"adcms/pkg/sms"
 	"adcms/pkg/utils"
+	"adcms/pkg/verifycode"
 	"context"
+	"errors"
 	"fmt"
-	"math/rand"
+	"net/http"
+	"strconv"
 	"time"
 
 	"github.com/gin-gonic/gin"
 )
 
 type AuthHandler struct {
-	userRepo *repository.UserRepository
+	userRepo      *repository.UserRepository
+	webauthnRepo  *repository.WebAuthnRepository
+	oidcRepo      *repository.OIDCRepository
+	apiKeyRepo    *repository.APIKeyRepository
+	authenticator authn.Authenticator
 }
 
 func NewAuthHandler() *AuthHandler {
 	return &AuthHandler{
-		userRepo: repository.NewUserRepository(),
+		userRepo:      repository.NewUserRepository(),
+		webauthnRepo:  repository.NewWebAuthnRepository(),
+		oidcRepo:      repository.NewOIDCRepository(),
+		apiKeyRepo:    repository.NewAPIKeyRepository(),
+		authenticator: authn.Default(),
 	}
 }
 
 type LoginRequest struct {
 	Username string `json:"username" binding:"required"`
 	Password string `json:"password" binding:"required"`
+	TenantID uint   `json:"tenant_id"` // 登录页所属租户，目录（LDAP）用户首次登录时需要
+	CaptchaFields
 }
 
 type LoginResponse struct {
-	Token       string `json:"token,omitempty"`
-	TempToken   string `json:"temp_token,omitempty"`
-	RequireTotp bool   `json:"require_totp"`
+	Token        string `json:"token,omitempty"`
+	RefreshToken string `json:"refresh_token,omitempty"`
+	ExpiresIn    int64  `json:"expires_in,omitempty"` // access token 有效期（秒）
+	TempToken    string `json:"temp_token,omitempty"`
+	RequireTotp  bool   `json:"require_totp"`
+	// MFAMethods 需要第二步验证时可用的方式：totp、webauthn
+	MFAMethods []string `json:"mfa_methods,omitempty"`
+	// RecoveryCodesLeft 使用恢复码登录时返回剩余可用数量，提示用户及时重新生成
+	RecoveryCodesLeft *int64 `json:"recovery_codes_left,omitempty"`
+	// PasswordExpired 密码已过期或被重置，需用 TempToken 调用 /auth/expired-password 修改密码后完成登录
+	PasswordExpired bool `json:"password_expired,omitempty"`
 }
 
+// refreshCookieName refresh token 同时以 HttpOnly Cookie 下发，仅在 /api/auth 路径携带
+const (
+	refreshCookieName = "refresh_token"
+	refreshCookiePath = "/api/auth"
+)
+
 // Login 用户登录
 // @Summary 用户登录
 // @Tags 认证
@@ -53,44 +85,95 @@ func (h *AuthHandler) Login(c *gin.Context) {
 		return
 	}
 
-	// 检查账号是否被锁定
-	if locked, remainSec := middleware.IsLoginLocked(req.Username); locked {
-		utils.Fail(c, 1011, fmt.Sprintf("账号已被锁定，请%d秒后再试", remainSec))
+	// 检查该 IP 对此账号的尝试是否被锁定
+	if locked, remainSec := middleware.IsLoginLocked(req.Username, c.ClientIP()); locked {
+		utils.Fail(c, 1011, fmt.Sprintf("尝试次数过多，请%d秒后再试", remainSec))
+		return
+	}
+	// 多次失败后先要求图形验证码，再到硬锁定
+	if !checkCaptcha(c, captcha.Login, req.Username, req.CaptchaFields) {
 		return
 	}
 
-	user, err := h.userRepo.FindByUsernameGlobal(req.Username)
-	if err != nil {
-		remaining, locked := middleware.RecordLoginFail(req.Username)
-		h.recordLoginLog(0, 0, req.Username, c.ClientIP(), c.Request.UserAgent(), 0, "用户不存在")
+	user, err := h.authenticator.Authenticate(authn.Credentials{
+		Username: req.Username,
+		Password: req.Password,
+		TenantID: req.TenantID,
+	})
+	// 目录账号已停用时密码未经校验，与密码错误同样处理，不向登录者透露账号状态
+	if errors.Is(err, authn.ErrUserNotFound) || errors.Is(err, authn.ErrInvalidPassword) || errors.Is(err, authn.ErrAccountDisabled) {
+		remaining, locked := middleware.RecordLoginFail(req.Username, c.ClientIP())
+		captcha.Login.Record(c.ClientIP(), req.Username)
+		if user != nil {
+			h.recordLoginLog(user.TenantID, user.ID, req.Username, c.ClientIP(), c.Request.UserAgent(), 0, err.Error())
+		} else {
+			h.recordLoginLog(0, 0, req.Username, c.ClientIP(), c.Request.UserAgent(), 0, err.Error())
+		}
 		if locked {
-			utils.Fail(c, 1011, "登录失败次数过多，账号已被锁定15分钟")
+			utils.Fail(c, 1011, "登录失败次数过多，请15分钟后再试")
 		} else {
-			utils.Fail(c, 1001, fmt.Sprintf("用户名或密码错误，还可尝试%d次", remaining))
+			utils.FailWithData(c, 1001, fmt.Sprintf("用户名或密码错误，还可尝试%d次", remaining),
+				gin.H{"captcha_required": captcha.Login.Required(c.ClientIP(), req.Username)})
 		}
 		return
 	}
+	if err != nil {
+		fmt.Printf("[Auth] 用户%s认证失败: %v\n", req.Username, err)
+		h.recordLoginLog(req.TenantID, 0, req.Username, c.ClientIP(), c.Request.UserAgent(), 0, "认证服务异常")
+		utils.Fail(c, 1013, "认证服务暂不可用，请稍后再试")
+		return
+	}
 
-	if !utils.ComparePassword(user.Password, req.Password) {
-		remaining, locked := middleware.RecordLoginFail(req.Username)
-		h.recordLoginLog(user.TenantID, user.ID, req.Username, c.ClientIP(), c.Request.UserAgent(), 0, "密码错误")
-		if locked {
-			utils.Fail(c, 1011, "登录失败次数过多，账号已被锁定15分钟")
-		} else {
-			utils.Fail(c, 1001, fmt.Sprintf("用户名或密码错误，还可尝试%d次", remaining))
+	captcha.Login.Clear(req.Username)
+	if !h.checkLoginAllowed(c, user) {
+		return
+	}
+
+	// 启用了 TOTP 或注册了通行密钥的用户需要第二步验证
+	if methods := h.mfaMethods(user); len(methods) > 0 {
+		tempToken, err := utils.GenerateTempToken(user.ID, user.TenantID, user.Username)
+		if err != nil {
+			utils.ServerError(c, "生成token失败")
+			return
 		}
+		utils.Success(c, LoginResponse{
+			TempToken:   tempToken,
+			RequireTotp: user.TOTPEnabled == 1,
+			MFAMethods:  methods,
+		})
+		return
+	}
+
+	if h.passwordChangeRequired(c, user) {
+		middleware.ClearLoginFail(req.Username, c.ClientIP())
+		return
+	}
+
+	resp, err := h.issueTokens(c, user)
+	if err != nil {
+		utils.ServerError(c, "生成token失败")
 		return
 	}
 
+	// 登录成功，清除失败记录
+	middleware.ClearLoginFail(req.Username, c.ClientIP())
+	h.userRepo.UpdateLoginInfo(user.ID, c.ClientIP())
+	h.recordLoginLog(user.TenantID, user.ID, req.Username, c.ClientIP(), c.Request.UserAgent(), 1, "登录成功")
+
+	utils.Success(c, resp)
+}
+
+// checkLoginAllowed 检查用户状态是否允许登录，不允许时直接返回错误
+func (h *AuthHandler) checkLoginAllowed(c *gin.Context, user *model.User) bool {
 	if user.Status == 0 {
-		h.recordLoginLog(user.TenantID, user.ID, req.Username, c.ClientIP(), c.Request.UserAgent(), 0, "用户已禁用")
+		h.recordLoginLog(user.TenantID, user.ID, user.Username, c.ClientIP(), c.Request.UserAgent(), 0, "用户已禁用")
 		utils.Fail(c, 1002, "用户已被禁用")
-		return
+		return false
 	}
 	if user.Status == 2 {
-		h.recordLoginLog(user.TenantID, user.ID, req.Username, c.ClientIP(), c.Request.UserAgent(), 0, "用户已锁定")
+		h.recordLoginLog(user.TenantID, user.ID, user.Username, c.ClientIP(), c.Request.UserAgent(), 0, "用户已锁定")
 		utils.Fail(c, 1012, "账号已被锁定，请联系管理员解锁")
-		return
+		return false
 	}
 
 	// 动态检查：用户长期未登录自动锁定（从 config_webs 读取天数，0=不锁定）
@@ -101,41 +184,110 @@ func (h *AuthHandler) Login(c *gin.Context) {
 			if user.LastLoginAt.Before(threshold) {
 				// 自动将状态设为锁定
 				h.userRepo.UpdateStatus(user.ID, 2)
-				h.recordLoginLog(user.TenantID, user.ID, req.Username, c.ClientIP(), c.Request.UserAgent(), 0, fmt.Sprintf("超过%d天未登录自动锁定", lockDays))
+				h.recordLoginLog(user.TenantID, user.ID, user.Username, c.ClientIP(), c.Request.UserAgent(), 0, fmt.Sprintf("超过%d天未登录自动锁定", lockDays))
 				utils.Fail(c, 1012, fmt.Sprintf("账号因超过%d天未登录已被锁定，请联系管理员解锁", lockDays))
-				return
+				return false
 			}
 		}
 	}
+	return true
+}
 
+// mfaMethods 用户可用的第二步验证方式
+func (h *AuthHandler) mfaMethods(user *model.User) []string {
+	var methods []string
 	if user.TOTPEnabled == 1 {
-		tempToken, err := utils.GenerateTempToken(user.ID, user.TenantID, user.Username)
-		if err != nil {
-			utils.ServerError(c, "生成token失败")
-			return
+		methods = append(methods, "totp")
+	}
+	if passkey.Enabled() {
+		if count, _ := h.webauthnRepo.CountByUser(user.ID); count > 0 {
+			methods = append(methods, "webauthn")
 		}
-		utils.Success(c, LoginResponse{
-			TempToken:   tempToken,
-			RequireTotp: true,
-		})
-		return
 	}
+	return methods
+}
 
-	token, err := utils.GenerateToken(user.ID, user.TenantID, user.Username, user.IsAdmin)
+// issueTokens 创建登录会话，签发 access token 与 refresh token
+func (h *AuthHandler) issueTokens(c *gin.Context, user *model.User) (*LoginResponse, error) {
+	sess := &session.Session{
+		UserID:    user.ID,
+		TenantID:  user.TenantID,
+		IP:        c.ClientIP(),
+		UserAgent: c.Request.UserAgent(),
+	}
+	refreshToken, err := session.Create(sess, utils.RefreshTokenTTL())
 	if err != nil {
-		utils.ServerError(c, "生成token失败")
+		return nil, err
+	}
+	h.checkLoginAlert(c, user)
+	// 刚完成登录视为已验证身份，短时间内进行敏感操作无需再次验证
+	return h.signTokens(c, user, sess.ID, refreshToken, time.Now())
+}
+
+// signTokens 为会话签发 access token，并下发 refresh token；authTime 为零值表示未验证身份（如刷新 token）
+func (h *AuthHandler) signTokens(c *gin.Context, user *model.User, sessionID, refreshToken string, authTime time.Time) (*LoginResponse, error) {
+	token, err := utils.GenerateAuthToken(user.ID, user.TenantID, user.Username, user.IsAdmin, user.TokenVersion, sessionID, authTime)
+	if err != nil {
+		return nil, err
+	}
+	setRefreshCookie(c, refreshToken, int(utils.RefreshTokenTTL().Seconds()))
+	return &LoginResponse{
+		Token:        token,
+		RefreshToken: refreshToken,
+		ExpiresIn:    int64(utils.AccessTokenTTL().Seconds()),
+	}, nil
+}
+
+func setRefreshCookie(c *gin.Context, value string, maxAge int) {
+	secure := c.Request.TLS != nil || c.GetHeader("X-Forwarded-Proto") == "https"
+	c.SetSameSite(http.SameSiteStrictMode)
+	c.SetCookie(refreshCookieName, value, maxAge, refreshCookiePath, "", secure, true)
+}
+
+type RefreshRequest struct {
+	RefreshToken string `json:"refresh_token"`
+}
+
+// Refresh 使用 refresh token 换取新的 access token，refresh token 每次使用后轮换
+// 已轮换的旧 refresh token 再次出现视为泄露，注销整个登录会话
+// @Summary 刷新token
+// @Tags 认证
+// @Accept json
+// @Produce json
+// @Param body body RefreshRequest false "refresh token（也可通过 Cookie 携带）"
+// @Success 200 {object} LoginResponse
+// @Router /auth/refresh [post]
+func (h *AuthHandler) Refresh(c *gin.Context) {
+	var req RefreshRequest
+	_ = c.ShouldBindJSON(&req)
+	if req.RefreshToken == "" {
+		req.RefreshToken, _ = c.Cookie(refreshCookieName)
+	}
+
+	sess, refreshToken, err := session.Rotate(req.RefreshToken)
+	if err != nil {
+		if errors.Is(err, session.ErrReused) {
+			h.recordLoginLog(0, 0, "", c.ClientIP(), c.Request.UserAgent(), 0, "refresh token重复使用，会话已注销")
+		}
+		setRefreshCookie(c, "", -1)
+		utils.Unauthorized(c, err.Error())
 		return
 	}
 
-	// 登录成功，清除失败记录
-	middleware.ClearLoginFail(req.Username)
-	h.userRepo.UpdateLoginInfo(user.ID, c.ClientIP())
-	h.recordLoginLog(user.TenantID, user.ID, req.Username, c.ClientIP(), c.Request.UserAgent(), 1, "登录成功")
+	user, err := h.userRepo.FindByID(sess.UserID)
+	if err != nil || user.Status != 1 {
+		session.Revoke(sess.ID)
+		setRefreshCookie(c, "", -1)
+		utils.Unauthorized(c, "用户不存在或已被禁用")
+		return
+	}
 
-	utils.Success(c, LoginResponse{
-		Token:       token,
-		RequireTotp: false,
-	})
+	resp, err := h.signTokens(c, user, sess.ID, refreshToken, time.Time{})
+	if err != nil {
+		utils.ServerError(c, "生成token失败")
+		return
+	}
+	utils.Success(c, resp)
 }
 
 type VerifyTOTPRequest struct {
@@ -162,24 +314,46 @@ func (h *AuthHandler) VerifyTOTP(c *gin.Context) {
 		return
 	}
 
+	if user.TOTPEnabled != 1 {
+		utils.Fail(c, 1009, "未启用TOTP")
+		return
+	}
+
+	// 动态验证码校验失败时尝试按恢复码核销（手机丢失等场景）
+	usedRecoveryCode := false
 	if !utils.ValidateTOTPCode(user.TOTPSecret, req.Code) {
-		utils.Fail(c, 1007, "验证码错误")
+		if !utils.IsRecoveryCode(req.Code) {
+			utils.Fail(c, 1007, "验证码错误")
+			return
+		}
+		ok, err := h.userRepo.UseRecoveryCode(user.ID, utils.HashRecoveryCode(req.Code))
+		if err != nil || !ok {
+			utils.Fail(c, 1007, "验证码错误")
+			return
+		}
+		usedRecoveryCode = true
+	}
+
+	if h.passwordChangeRequired(c, user) {
 		return
 	}
 
-	token, err := utils.GenerateToken(user.ID, user.TenantID, user.Username, user.IsAdmin)
+	resp, err := h.issueTokens(c, user)
 	if err != nil {
 		utils.ServerError(c, "生成token失败")
 		return
 	}
 
 	h.userRepo.UpdateLoginInfo(user.ID, c.ClientIP())
-	h.recordLoginLog(user.TenantID, user.ID, user.Username, c.ClientIP(), c.Request.UserAgent(), 1, "登录成功(TOTP)")
+	if usedRecoveryCode {
+		left, _ := h.userRepo.CountRecoveryCodes(user.ID)
+		resp.RecoveryCodesLeft = &left
+		h.recordLoginLog(user.TenantID, user.ID, user.Username, c.ClientIP(), c.Request.UserAgent(), 1, fmt.Sprintf("登录成功(恢复码，剩余%d个)", left))
+	} else {
+		h.recordLoginLog(user.TenantID, user.ID, user.Username, c.ClientIP(), c.Request.UserAgent(), 1, "登录成功(TOTP)")
+	}
 
-	utils.Success(c, LoginResponse{
-		Token:       token,
-		RequireTotp: false,
-	})
+	utils.Success(c, resp)
 }
 
 type GenerateTOTPResponse struct {
@@ -253,7 +427,77 @@ func (h *AuthHandler) BindTOTP(c *gin.Context) {
 
 	database.RDB.Del(ctx, "totp:temp:"+user.Username)
 
-	utils.SuccessWithMessage(c, "绑定成功", nil)
+	codes, err := h.resetRecoveryCodes(userID)
+	if err != nil {
+		utils.ServerError(c, "生成恢复码失败")
+		return
+	}
+
+	// 恢复码仅在此时明文返回一次，不写入操作日志
+	middleware.OmitResponseLog(c)
+	utils.SuccessWithMessage(c, "绑定成功", gin.H{"recovery_codes": codes})
+}
+
+// resetRecoveryCodes 生成新的一组恢复码并作废旧恢复码
+func (h *AuthHandler) resetRecoveryCodes(userID uint) ([]string, error) {
+	codes, err := utils.GenerateRecoveryCodes(utils.RecoveryCodeCount)
+	if err != nil {
+		return nil, err
+	}
+	hashes := make([]string, len(codes))
+	for i, code := range codes {
+		hashes[i] = utils.HashRecoveryCode(code)
+	}
+	if err := h.userRepo.ReplaceRecoveryCodes(userID, hashes); err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
+
+// RecoveryCodes 查询剩余可用的恢复码数量
+func (h *AuthHandler) RecoveryCodes(c *gin.Context) {
+	userID := middleware.GetUserID(c)
+	count, err := h.userRepo.CountRecoveryCodes(userID)
+	if err != nil {
+		utils.ServerError(c, "查询失败")
+		return
+	}
+	utils.Success(c, gin.H{"remaining": count})
+}
+
+// RegenerateRecoveryCodes 校验动态验证码后重新生成恢复码，旧恢复码全部作废
+func (h *AuthHandler) RegenerateRecoveryCodes(c *gin.Context) {
+	var req BindTOTPRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.BadRequest(c, "参数错误")
+		return
+	}
+
+	userID := middleware.GetUserID(c)
+	user, err := h.userRepo.FindByID(userID)
+	if err != nil {
+		utils.Fail(c, 1006, "用户不存在")
+		return
+	}
+
+	if user.TOTPEnabled != 1 {
+		utils.Fail(c, 1009, "未启用TOTP")
+		return
+	}
+
+	if !utils.ValidateTOTPCode(user.TOTPSecret, req.Code) {
+		utils.Fail(c, 1007, "验证码错误")
+		return
+	}
+
+	codes, err := h.resetRecoveryCodes(userID)
+	if err != nil {
+		utils.ServerError(c, "生成恢复码失败")
+		return
+	}
+
+	middleware.OmitResponseLog(c)
+	utils.SuccessWithMessage(c, "恢复码已重新生成", gin.H{"recovery_codes": codes})
 }
 
 func (h *AuthHandler) DisableTOTP(c *gin.Context) {
@@ -284,6 +528,7 @@ func (h *AuthHandler) DisableTOTP(c *gin.Context) {
 		utils.ServerError(c, "解绑失败")
 		return
 	}
+	h.userRepo.DeleteRecoveryCodes(userID)
 
 	utils.SuccessWithMessage(c, "解绑成功", nil)
 }
@@ -301,6 +546,8 @@ type UserInfoResponse struct {
 	IsAdmin     int8     `json:"is_admin"`
 	Roles       []string `json:"roles"`
 	Permissions []string `json:"permissions"`
+	// Impersonator 超管代登录时为超管用户名，前端据此提示并提供结束代登录入口
+	Impersonator string `json:"impersonator,omitempty"`
 }
 
 func (h *AuthHandler) GetUserInfo(c *gin.Context) {
@@ -323,18 +570,19 @@ func (h *AuthHandler) GetUserInfo(c *gin.Context) {
 	}
 
 	utils.Success(c, UserInfoResponse{
-		ID:          user.ID,
-		Username:    user.Username,
-		Nickname:    user.Nickname,
-		Email:       user.Email,
-		Phone:       user.Phone,
-		Avatar:      user.Avatar,
-		TenantID:    user.TenantID,
-		TOTPEnabled: user.TOTPEnabled == 1,
-		EmailNotify: user.EmailNotify,
-		IsAdmin:     user.IsAdmin,
-		Roles:       roleNames,
-		Permissions: permissions,
+		ID:           user.ID,
+		Username:     user.Username,
+		Nickname:     user.Nickname,
+		Email:        user.Email,
+		Phone:        user.Phone,
+		Avatar:       user.Avatar,
+		TenantID:     user.TenantID,
+		TOTPEnabled:  user.TOTPEnabled == 1,
+		EmailNotify:  user.EmailNotify,
+		IsAdmin:      user.IsAdmin,
+		Roles:        roleNames,
+		Permissions:  permissions,
+		Impersonator: h.impersonatorName(c),
 	})
 }
 
@@ -376,10 +624,13 @@ func (h *AuthHandler) UpdateUserInfo(c *gin.Context) {
 
 type ChangePasswordRequest struct {
 	OldPassword string `json:"old_password" binding:"required"`
-	NewPassword string `json:"new_password" binding:"required,min=6"`
+	NewPassword string `json:"new_password" binding:"required"`
 }
 
 func (h *AuthHandler) ChangePassword(c *gin.Context) {
+	// 请求中含明文密码，不写入操作日志
+	middleware.OmitRequestLog(c)
+
 	var req ChangePasswordRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
 		utils.BadRequest(c, "参数错误")
@@ -397,6 +648,9 @@ func (h *AuthHandler) ChangePassword(c *gin.Context) {
 		utils.Fail(c, 1010, "原密码错误")
 		return
 	}
+	if !checkNewPassword(c, h.userRepo, user, req.NewPassword) {
+		return
+	}
 
 	hashedPassword, err := utils.HashPassword(req.NewPassword)
 	if err != nil {
@@ -409,14 +663,22 @@ func (h *AuthHandler) ChangePassword(c *gin.Context) {
 		return
 	}
 
+	// 保留当前会话（需用 refresh token 换取新 token），注销其他设备上的登录
+	session.RevokeUser(userID, middleware.GetSessionID(c))
+	session.BumpVersion(userID)
+
 	utils.SuccessWithMessage(c, "密码修改成功", nil)
 }
 
 func (h *AuthHandler) Logout(c *gin.Context) {
+	// 注销整个登录会话，其下的 access/refresh token 全部失效
+	session.Revoke(middleware.GetSessionID(c))
+	setRefreshCookie(c, "", -1)
+
 	authHeader := c.GetHeader("Authorization")
 	if authHeader != "" {
 		token := authHeader[7:]
-		middleware.BlacklistToken(token, 24*time.Hour)
+		middleware.BlacklistToken(token, utils.AccessTokenTTL())
 	}
 
 	utils.SuccessWithMessage(c, "登出成功", nil)
@@ -434,6 +696,7 @@ func (h *AuthHandler) GetPermissionCodes(c *gin.Context) {
 
 type ForgotPasswordRequest struct {
 	Email string `json:"email" binding:"required,email"`
+	CaptchaFields
 }
 
 func (h *AuthHandler) ForgotPassword(c *gin.Context) {
@@ -443,46 +706,34 @@ func (h *AuthHandler) ForgotPassword(c *gin.Context) {
 		return
 	}
 
-	ctx := context.Background()
-
-	// 限流：同一邮箱1分钟内只能发1次
-	rateLimitKey := "pwd_reset_limit:" + req.Email
-	if database.RDB.Exists(ctx, rateLimitKey).Val() > 0 {
-		utils.Fail(c, 1020, "发送过于频繁，请1分钟后再试")
+	if !checkCaptcha(c, captcha.ForgotPassword, req.Email, req.CaptchaFields) {
 		return
 	}
+	captcha.ForgotPassword.Record(c.ClientIP(), req.Email)
 
-	// 查找用户
-	user, err := h.userRepo.FindByEmailGlobal(req.Email)
-	if err != nil {
-		// 为防止邮箱枚举攻击，即使用户不存在也返回成功
-		utils.SuccessWithMessage(c, "如果该邮箱已注册，验证码将发送到您的邮箱", nil)
+	// 无论邮箱是否注册都占用发送频率，避免通过限流差异枚举邮箱
+	code, err := verifycode.Issue(verifycode.PurposeResetPassword, req.Email)
+	if !checkIssueError(c, err) {
 		return
 	}
 
-	// 生成6位验证码
-	code := fmt.Sprintf("%06d", rand.Intn(1000000))
-
-	// 存入Redis，5分钟过期
-	codeKey := "pwd_reset:" + req.Email
-	database.RDB.Set(ctx, codeKey, code, 5*time.Minute)
-	database.RDB.Set(ctx, rateLimitKey, "1", time.Minute)
-
-	// 异步发送邮件
-	go func() {
-		if err := email.SendResetCode(req.Email, code); err != nil {
-			fmt.Printf("[Email] 发送重置验证码失败: %v\n", err)
-		}
-	}()
+	// 为防止邮箱枚举攻击，即使用户不存在也返回成功
+	if _, err := h.userRepo.FindByEmailGlobal(req.Email); err == nil {
+		// 异步发送邮件
+		go func() {
+			if err := email.SendResetCode(req.Email, code); err != nil {
+				fmt.Printf("[Email] 发送重置验证码失败: %v\n", err)
+			}
+		}()
+	}
 
-	_ = user
 	utils.SuccessWithMessage(c, "如果该邮箱已注册，验证码将发送到您的邮箱", nil)
 }
 
 type ResetPasswordByEmailRequest struct {
 	Email       string `json:"email" binding:"required,email"`
 	Code        string `json:"code" binding:"required,len=6"`
-	NewPassword string `json:"new_password" binding:"required,min=6"`
+	NewPassword string `json:"new_password" binding:"required"`
 }
 
 func (h *AuthHandler) ResetPasswordByEmail(c *gin.Context) {
@@ -492,13 +743,13 @@ func (h *AuthHandler) ResetPasswordByEmail(c *gin.Context) {
 		return
 	}
 
-	ctx := context.Background()
-	codeKey := "pwd_reset:" + req.Email
-
-	// 验证验证码
-	storedCode, err := database.RDB.Get(ctx, codeKey).Result()
-	if err != nil || storedCode != req.Code {
-		utils.Fail(c, 1021, "验证码错误或已过期")
+	// 验证验证码，通过后立即作废，并发请求中只有一个能继续
+	err := verifycode.Check(verifycode.PurposeResetPassword, req.Email, req.Code)
+	if err == nil && !verifycode.Consume(verifycode.PurposeResetPassword, req.Email) {
+		err = verifycode.ErrInvalid
+	}
+	if err != nil {
+		utils.Fail(c, 1021, err.Error())
 		return
 	}
 
@@ -508,6 +759,9 @@ func (h *AuthHandler) ResetPasswordByEmail(c *gin.Context) {
 		utils.Fail(c, 1006, "该邮箱未注册")
 		return
 	}
+	if !checkNewPassword(c, h.userRepo, user, req.NewPassword) {
+		return
+	}
 
 	// 重置密码
 	hashedPassword, err := utils.HashPassword(req.NewPassword)
@@ -521,12 +775,46 @@ func (h *AuthHandler) ResetPasswordByEmail(c *gin.Context) {
 		return
 	}
 
-	// 删除验证码
-	database.RDB.Del(ctx, codeKey)
+	// 密码已重置，注销该用户所有登录会话并删除 API Key
+	session.RevokeUser(user.ID, "")
+	session.BumpVersion(user.ID)
+	h.apiKeyRepo.DeleteByUser(user.ID)
 
 	utils.SuccessWithMessage(c, "密码重置成功", nil)
 }
 
+// Sessions 列出当前用户已登录的设备
+func (h *AuthHandler) Sessions(c *gin.Context) {
+	sessions, err := session.List(middleware.GetUserID(c))
+	if err != nil {
+		utils.ServerError(c, "查询失败")
+		return
+	}
+	current := middleware.GetSessionID(c)
+	for _, s := range sessions {
+		s.Current = s.ID == current
+	}
+	utils.Success(c, sessions)
+}
+
+// RevokeSession 注销当前用户的某个登录设备
+func (h *AuthHandler) RevokeSession(c *gin.Context) {
+	id := c.Param("id")
+	sess, err := session.Get(id)
+	if err != nil || sess.UserID != middleware.GetUserID(c) {
+		utils.Fail(c, 1015, "会话不存在或已失效")
+		return
+	}
+	if err := session.Revoke(id); err != nil {
+		utils.ServerError(c, "注销失败")
+		return
+	}
+	if id == middleware.GetSessionID(c) {
+		setRefreshCookie(c, "", -1)
+	}
+	utils.SuccessWithMessage(c, "已注销该设备", nil)
+}
+
 // LoginHistory 获取当前用户最近登录记录
 func (h *AuthHandler) LoginHistory(c *gin.Context) {
 	userID := middleware.GetUserID(c)
@@ -543,6 +831,7 @@ func (h *AuthHandler) LoginHistory(c *gin.Context) {
 // SendSmsCode 发送手机验证码
 type SendSmsCodeRequest struct {
 	Phone string `json:"phone" binding:"required"`
+	CaptchaFields
 }
 
 func (h *AuthHandler) SendSmsCode(c *gin.Context) {
@@ -553,22 +842,16 @@ func (h *AuthHandler) SendSmsCode(c *gin.Context) {
 	}
 
 	userID := middleware.GetUserID(c)
-	ctx := context.Background()
-
-	// 限流：同一用户1分钟内只能发1次
-	rateLimitKey := fmt.Sprintf("sms_limit:%d", userID)
-	if database.RDB.Exists(ctx, rateLimitKey).Val() > 0 {
-		utils.Fail(c, 1020, "发送过于频繁，请1分钟后再试")
+	subject := strconv.FormatUint(uint64(userID), 10)
+	if !checkCaptcha(c, captcha.SmsCode, subject, req.CaptchaFields) {
 		return
 	}
+	captcha.SmsCode.Record(c.ClientIP(), subject)
 
-	// 生成6位验证码
-	code := fmt.Sprintf("%06d", rand.Intn(1000000))
-
-	// 存入Redis，5分钟过期
-	codeKey := fmt.Sprintf("sms_code:%d", userID)
-	database.RDB.Set(ctx, codeKey, code+":"+req.Phone, 5*time.Minute)
-	database.RDB.Set(ctx, rateLimitKey, "1", time.Minute)
+	code, err := verifycode.Issue(verifycode.PurposeBindPhone, req.Phone)
+	if !checkIssueError(c, err) {
+		return
+	}
 
 	// 异步发送短信
 	go func() {
@@ -593,26 +876,18 @@ func (h *AuthHandler) BindPhone(c *gin.Context) {
 		return
 	}
 
-	userID := middleware.GetUserID(c)
-	ctx := context.Background()
-	codeKey := fmt.Sprintf("sms_code:%d", userID)
-
-	// 验证验证码
-	stored, err := database.RDB.Get(ctx, codeKey).Result()
-	if err != nil {
-		utils.Fail(c, 1021, "验证码已过期，请重新发送")
-		return
+	// 验证验证码，通过后立即作废，并发请求中只有一个能继续
+	err := verifycode.Check(verifycode.PurposeBindPhone, req.Phone, req.Code)
+	if err == nil && !verifycode.Consume(verifycode.PurposeBindPhone, req.Phone) {
+		err = verifycode.ErrInvalid
 	}
-
-	// stored 格式: "code:phone"
-	parts := fmt.Sprintf("%s:%s", req.Code, req.Phone)
-	if stored != parts {
-		utils.Fail(c, 1021, "验证码错误")
+	if err != nil {
+		utils.Fail(c, 1021, err.Error())
 		return
 	}
 
 	// 更新手机号
-	user, err := h.userRepo.FindByID(userID)
+	user, err := h.userRepo.FindByID(middleware.GetUserID(c))
 	if err != nil {
 		utils.Fail(c, 1006, "用户不存在")
 		return
@@ -624,9 +899,6 @@ func (h *AuthHandler) BindPhone(c *gin.Context) {
 		return
 	}
 
-	// 删除验证码
-	database.RDB.Del(ctx, codeKey)
-
 	utils.SuccessWithMessage(c, "手机绑定成功", nil)
 }
 
@@ -652,6 +924,7 @@ func (h *AuthHandler) recordLoginLog(tenantID, userID uint, username, ip, userAg
 		Username:  username,
 		IP:        ip,
 		UserAgent: userAgent,
+		Location:  iplocate.Lookup(ip).String(),
 		Status:    status,
 		Message:   message,
 		CreatedAt: time.Now(),
@@ -0,0 +1,47 @@
+package handler
+
+import (
+	"adcms/pkg/captcha"
+	"adcms/pkg/utils"
+	"fmt"
+
+	"github.com/gin-gonic/gin"
+)
+
+// CaptchaFields 需要图形验证码的请求携带的字段
+type CaptchaFields struct {
+	CaptchaID   string `json:"captcha_id"`
+	CaptchaCode string `json:"captcha_code"`
+}
+
+// Captcha 获取图形验证码
+// @Summary 获取图形验证码
+// @Tags 认证
+// @Produce json
+// @Success 200 {object} captcha.Challenge
+// @Router /auth/captcha [get]
+func (h *AuthHandler) Captcha(c *gin.Context) {
+	challenge, err := captcha.New()
+	if err != nil {
+		fmt.Printf("[Captcha] 生成验证码失败: %v\n", err)
+		utils.ServerError(c, "生成验证码失败")
+		return
+	}
+	utils.Success(c, challenge)
+}
+
+// checkCaptcha 场景风险达到阈值后要求图形验证码，未通过时直接返回错误
+func checkCaptcha(c *gin.Context, scene captcha.Scene, subject string, fields CaptchaFields) bool {
+	if !scene.Required(c.ClientIP(), subject) {
+		return true
+	}
+	if fields.CaptchaID == "" || fields.CaptchaCode == "" {
+		utils.FailWithData(c, 1022, "请输入图形验证码", gin.H{"captcha_required": true})
+		return false
+	}
+	if !captcha.Verify(fields.CaptchaID, fields.CaptchaCode) {
+		utils.FailWithData(c, 1023, "图形验证码错误", gin.H{"captcha_required": true})
+		return false
+	}
+	return true
+}
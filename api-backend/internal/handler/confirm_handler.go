@@ -0,0 +1,108 @@
+package handler
+
+import (
+	"adcms/internal/authn"
+	"adcms/internal/middleware"
+	"adcms/pkg/utils"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+type ConfirmRequest struct {
+	Password string `json:"password"`
+	TOTPCode string `json:"totp_code"` // 启用了两步验证时可用验证码代替密码
+}
+
+type ConfirmResponse struct {
+	Token     string `json:"token"`
+	ExpiresIn int64  `json:"expires_in"`
+	AuthTime  int64  `json:"auth_time"`
+}
+
+// Confirm 重新验证身份，签发带最新验证时间的 access token（会话不变），用于调用敏感操作
+// @Summary 敏感操作前重新验证身份
+// @Tags 认证
+// @Accept json
+// @Produce json
+// @Param body body ConfirmRequest true "密码或两步验证码"
+// @Success 200 {object} ConfirmResponse
+// @Router /auth/confirm [post]
+func (h *AuthHandler) Confirm(c *gin.Context) {
+	// 请求中含密码，响应中含新 token，均不写入操作日志
+	middleware.OmitRequestLog(c)
+	middleware.OmitResponseLog(c)
+
+	var req ConfirmRequest
+	if err := c.ShouldBindJSON(&req); err != nil || (req.Password == "" && req.TOTPCode == "") {
+		utils.BadRequest(c, "请输入密码或两步验证码")
+		return
+	}
+
+	sessionID := middleware.GetSessionID(c)
+	if sessionID == "" {
+		utils.Forbidden(c, "请使用账号登录后操作")
+		return
+	}
+	user, err := h.userRepo.FindByID(middleware.GetUserID(c))
+	if err != nil {
+		utils.Fail(c, 1006, "用户不存在")
+		return
+	}
+
+	// 与登录共用失败计数，防止借已登录的 token 暴力尝试密码
+	if locked, remainSec := middleware.IsLoginLocked(user.Username, c.ClientIP()); locked {
+		utils.Fail(c, 1011, fmt.Sprintf("尝试次数过多，请%d秒后再试", remainSec))
+		return
+	}
+
+	if req.TOTPCode != "" {
+		if user.TOTPEnabled != 1 {
+			utils.Fail(c, 1009, "未启用TOTP")
+			return
+		}
+		if !utils.ValidateTOTPCode(user.TOTPSecret, req.TOTPCode) {
+			h.confirmFailed(c, user.Username, "验证码错误")
+			return
+		}
+	} else {
+		authed, err := h.authenticator.Authenticate(authn.Credentials{Username: user.Username, Password: req.Password})
+		if errors.Is(err, authn.ErrUserNotFound) || errors.Is(err, authn.ErrInvalidPassword) || (err == nil && authed.ID != user.ID) {
+			h.confirmFailed(c, user.Username, "密码错误")
+			return
+		}
+		if errors.Is(err, authn.ErrAccountDisabled) {
+			utils.Fail(c, 1002, "用户已被禁用")
+			return
+		}
+		if err != nil {
+			fmt.Printf("[Auth] 用户%s重新验证身份失败: %v\n", user.Username, err)
+			utils.Fail(c, 1013, "认证服务暂不可用，请稍后再试")
+			return
+		}
+	}
+	middleware.ClearLoginFail(user.Username, c.ClientIP())
+
+	now := time.Now()
+	token, err := utils.GenerateAuthToken(user.ID, user.TenantID, user.Username, user.IsAdmin, user.TokenVersion, sessionID, now)
+	if err != nil {
+		utils.ServerError(c, "生成token失败")
+		return
+	}
+	utils.Success(c, ConfirmResponse{
+		Token:     token,
+		ExpiresIn: int64(utils.AccessTokenTTL().Seconds()),
+		AuthTime:  now.Unix(),
+	})
+}
+
+func (h *AuthHandler) confirmFailed(c *gin.Context, username, message string) {
+	remaining, locked := middleware.RecordLoginFail(username, c.ClientIP())
+	if locked {
+		utils.Fail(c, 1011, "失败次数过多，请15分钟后再试")
+		return
+	}
+	utils.Fail(c, 1010, fmt.Sprintf("%s，还可尝试%d次", message, remaining))
+}
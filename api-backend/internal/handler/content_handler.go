@@ -385,6 +385,22 @@ func (h *MediaHandler) Upload(c *gin.Context) {
 	utils.Success(c, media)
 }
 
+// UploadTemp 上传临时文件（预览等），不写入媒体库，超过存活时间后由定时任务清理
+func (h *MediaHandler) UploadTemp(c *gin.Context) {
+	file, err := c.FormFile("file")
+	if err != nil {
+		utils.BadRequest(c, "请选择文件")
+		return
+	}
+
+	fileInfo, err := storage.UploadTemp(file, middleware.GetTenantID(c))
+	if err != nil {
+		utils.ServerError(c, "文件上传失败: "+err.Error())
+		return
+	}
+	utils.Success(c, fileInfo)
+}
+
 func (h *MediaHandler) Delete(c *gin.Context) {
 	id, _ := strconv.ParseUint(c.Param("id"), 10, 64)
 
@@ -4,8 +4,11 @@ import (
 	"adcms/internal/middleware"
 	"adcms/internal/model"
 	"adcms/internal/repository"
+	"adcms/pkg/crontab"
 	"adcms/pkg/utils"
+	"errors"
 	"strconv"
+	"time"
 
 	"github.com/gin-gonic/gin"
 )
@@ -36,11 +39,22 @@ func (h *CrontabHandler) List(c *gin.Context) {
 }
 
 func (h *CrontabHandler) Create(c *gin.Context) {
+	if !checkJobManager(c) {
+		return
+	}
+
 	var req struct {
 		Name       string `json:"name" binding:"required"`
+		Type       int8   `json:"type"`
 		Expression string `json:"expression" binding:"required"`
-		Command    string `json:"command" binding:"required"`
+		Command    string `json:"command"`
+		Content    string `json:"content"`
+		Maximums   int    `json:"maximums"`
+		Timeout    int    `json:"timeout"`
+		Retries    int    `json:"retries"`
+		Overlap    int8   `json:"overlap"`
 		Status     int8   `json:"status"`
+		Sort       int    `json:"sort"`
 		Remark     string `json:"remark"`
 	}
 	if err := c.ShouldBindJSON(&req); err != nil {
@@ -49,76 +63,290 @@ func (h *CrontabHandler) Create(c *gin.Context) {
 	}
 
 	tenantID := middleware.GetTenantID(c)
-	crontab := model.Crontab{
+	job := model.Crontab{
 		TenantBaseModel: model.TenantBaseModel{TenantID: tenantID},
 		Name:            req.Name,
+		Type:            req.Type,
 		Expression:      req.Expression,
 		Command:         req.Command,
+		Content:         req.Content,
+		Maximums:        req.Maximums,
+		Timeout:         req.Timeout,
+		Retries:         req.Retries,
+		Overlap:         req.Overlap,
 		Status:          req.Status,
+		Sort:            req.Sort,
 		Remark:          req.Remark,
 	}
+	if !checkJobType(c, &job) {
+		return
+	}
+	if err := crontab.Validate(&job); err != nil {
+		utils.Fail(c, 10002, err.Error())
+		return
+	}
 
-	if err := h.crontabRepo.Create(&crontab); err != nil {
+	if err := h.crontabRepo.Create(&job); err != nil {
 		utils.ServerError(c, "创建失败")
 		return
 	}
-	utils.SuccessWithMessage(c, "创建成功", crontab)
+	if err := crontab.Reload(&job); err != nil {
+		utils.Fail(c, 10001, "任务已保存，但调度失败: "+err.Error())
+		return
+	}
+	utils.SuccessWithMessage(c, "创建成功", job)
 }
 
 func (h *CrontabHandler) Update(c *gin.Context) {
-	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
-	if err != nil {
-		utils.BadRequest(c, "参数错误")
+	if !checkJobManager(c) {
 		return
 	}
 
+	// 字段均为指针，只更新请求中提供的字段
 	var req struct {
-		Name       string `json:"name"`
-		Expression string `json:"expression"`
-		Command    string `json:"command"`
-		Status     int8   `json:"status"`
-		Remark     string `json:"remark"`
+		Name       *string `json:"name"`
+		Type       *int8   `json:"type"`
+		Expression *string `json:"expression"`
+		Command    *string `json:"command"`
+		Content    *string `json:"content"`
+		Maximums   *int    `json:"maximums"`
+		Timeout    *int    `json:"timeout"`
+		Retries    *int    `json:"retries"`
+		Overlap    *int8   `json:"overlap"`
+		Status     *int8   `json:"status"`
+		Sort       *int    `json:"sort"`
+		Remark     *string `json:"remark"`
 	}
 	if err := c.ShouldBindJSON(&req); err != nil {
 		utils.BadRequest(c, "参数错误")
 		return
 	}
 
-	crontab, err := h.crontabRepo.FindByID(uint(id))
-	if err != nil {
-		utils.Fail(c, 404, "定时任务不存在")
+	job := h.findJob(c)
+	if job == nil {
 		return
 	}
 
-	if req.Name != "" {
-		crontab.Name = req.Name
+	if req.Name != nil && *req.Name != "" {
+		job.Name = *req.Name
+	}
+	if req.Expression != nil && *req.Expression != "" {
+		job.Expression = *req.Expression
+	}
+	// 系统任务只允许修改名称、执行时间等调度属性，不可更改执行内容
+	if job.IsSystem != 1 {
+		if req.Type != nil {
+			job.Type = *req.Type
+		}
+		if req.Command != nil && *req.Command != "" {
+			job.Command = *req.Command
+		}
+		if req.Content != nil {
+			job.Content = *req.Content
+		}
+	}
+	if req.Maximums != nil {
+		job.Maximums = *req.Maximums
+	}
+	if req.Timeout != nil {
+		job.Timeout = *req.Timeout
+	}
+	if req.Retries != nil {
+		job.Retries = *req.Retries
+	}
+	if req.Overlap != nil {
+		job.Overlap = *req.Overlap
+	}
+	if req.Status != nil {
+		job.Status = *req.Status
+	}
+	if req.Sort != nil {
+		job.Sort = *req.Sort
 	}
-	if req.Expression != "" {
-		crontab.Expression = req.Expression
+	if req.Remark != nil {
+		job.Remark = *req.Remark
 	}
-	if req.Command != "" {
-		crontab.Command = req.Command
+	if !checkJobType(c, job) {
+		return
+	}
+	if err := crontab.Validate(job); err != nil {
+		utils.Fail(c, 10002, err.Error())
+		return
 	}
-	crontab.Status = req.Status
-	crontab.Remark = req.Remark
 
-	if err := h.crontabRepo.Update(crontab); err != nil {
+	if err := h.crontabRepo.Update(job); err != nil {
 		utils.ServerError(c, "更新失败")
 		return
 	}
-	utils.SuccessWithMessage(c, "更新成功", crontab)
+	if err := crontab.Reload(job); err != nil {
+		utils.Fail(c, 10001, "任务已保存，但调度失败: "+err.Error())
+		return
+	}
+	utils.SuccessWithMessage(c, "更新成功", job)
 }
 
 func (h *CrontabHandler) Delete(c *gin.Context) {
+	if !checkJobManager(c) {
+		return
+	}
+	job := h.findJob(c)
+	if job == nil {
+		return
+	}
+	if job.IsSystem == 1 {
+		utils.Fail(c, 10003, "系统任务不可删除，可暂停")
+		return
+	}
+
+	if err := h.crontabRepo.Delete(job.ID); err != nil {
+		utils.ServerError(c, "删除失败")
+		return
+	}
+	crontab.Remove(job.ID)
+	utils.SuccessWithMessage(c, "删除成功", nil)
+}
+
+// Builtins 列出可供 Command 引用的内置任务
+func (h *CrontabHandler) Builtins(c *gin.Context) {
+	utils.Success(c, crontab.Builtins())
+}
+
+// Preview 预览表达式接下来的执行时间（服务器时区）
+func (h *CrontabHandler) Preview(c *gin.Context) {
+	var req struct {
+		Expression string `json:"expression" binding:"required"`
+		Count      int    `json:"count"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.BadRequest(c, "参数错误")
+		return
+	}
+	if req.Count <= 0 {
+		req.Count = 5
+	}
+
+	now := time.Now()
+	runs, err := crontab.NextRuns(req.Expression, req.Count, now)
+	if err != nil {
+		utils.Fail(c, 10002, err.Error())
+		return
+	}
+	times := make([]string, 0, len(runs))
+	for _, t := range runs {
+		times = append(times, t.Format("2006-01-02 15:04:05"))
+	}
+	zone, _ := now.Zone()
+	utils.Success(c, gin.H{
+		"timezone": zone,
+		"runs":     times,
+	})
+}
+
+// Run 立即执行一次任务，执行结果写入执行记录
+func (h *CrontabHandler) Run(c *gin.Context) {
+	if !checkJobManager(c) {
+		return
+	}
+	job := h.findJob(c)
+	if job == nil {
+		return
+	}
+	if err := crontab.RunNow(job.ID); err != nil {
+		if errors.Is(err, crontab.ErrRunning) {
+			utils.Fail(c, 10004, "任务正在执行中，请稍后再试")
+			return
+		}
+		utils.ServerError(c, "执行失败")
+		return
+	}
+	utils.SuccessWithMessage(c, "任务已触发，请稍后查看执行记录", nil)
+}
+
+// Pause 暂停任务
+func (h *CrontabHandler) Pause(c *gin.Context) {
+	if !checkJobManager(c) {
+		return
+	}
+	job := h.findJob(c)
+	if job == nil {
+		return
+	}
+	if err := crontab.Pause(job.ID); err != nil {
+		utils.ServerError(c, "暂停失败")
+		return
+	}
+	utils.SuccessWithMessage(c, "已暂停", nil)
+}
+
+// Resume 恢复任务
+func (h *CrontabHandler) Resume(c *gin.Context) {
+	if !checkJobManager(c) {
+		return
+	}
+	job := h.findJob(c)
+	if job == nil {
+		return
+	}
+	if err := crontab.Resume(job.ID); err != nil {
+		utils.Fail(c, 10001, "恢复失败: "+err.Error())
+		return
+	}
+	utils.SuccessWithMessage(c, "已恢复", nil)
+}
+
+// checkJobManager 定时任务路由未纳入权限表，增删改及执行、暂停、恢复仅管理员可操作
+func checkJobManager(c *gin.Context) bool {
+	if middleware.GetIsAdmin(c) == 0 {
+		utils.Forbidden(c, "仅管理员可管理定时任务")
+		return false
+	}
+	return true
+}
+
+// checkJobType HTTP 回调可访问内网地址、SQL 维护作用于所有租户的数据，仅超管可创建或修改
+func checkJobType(c *gin.Context, job *model.Crontab) bool {
+	if job.Type != model.CrontabTypeBuiltin && middleware.GetIsAdmin(c) != 2 {
+		utils.Forbidden(c, "仅超级管理员可配置HTTP回调或SQL维护任务")
+		return false
+	}
+	return true
+}
+
+// findJob 按路径参数查找任务并校验租户，失败时已写入响应并返回 nil
+func (h *CrontabHandler) findJob(c *gin.Context) *model.Crontab {
 	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
 	if err != nil {
 		utils.BadRequest(c, "参数错误")
+		return nil
+	}
+
+	job, err := h.crontabRepo.FindByID(uint(id))
+	if err != nil {
+		utils.Fail(c, 404, "定时任务不存在")
+		return nil
+	}
+	if middleware.GetIsAdmin(c) != 2 && job.TenantID != middleware.GetTenantID(c) {
+		utils.Forbidden(c, "无权操作该任务")
+		return nil
+	}
+	return job
+}
+
+// Logs 分页查询任务执行记录
+func (h *CrontabHandler) Logs(c *gin.Context) {
+	job := h.findJob(c)
+	if job == nil {
 		return
 	}
 
-	if err := h.crontabRepo.Delete(uint(id)); err != nil {
-		utils.ServerError(c, "删除失败")
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "20"))
+	status := c.Query("status")
+
+	logs, total, err := h.crontabRepo.ListLogs(job.ID, page, pageSize, status)
+	if err != nil {
+		utils.ServerError(c, "查询失败")
 		return
 	}
-	utils.SuccessWithMessage(c, "删除成功", nil)
+	utils.SuccessWithPage(c, logs, total, page, pageSize)
 }
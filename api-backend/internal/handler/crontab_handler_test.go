@@ -0,0 +1,43 @@
+package handler
+
+import (
+	"adcms/internal/middleware"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 普通用户不能增删改、执行、暂停或恢复定时任务，在查库之前即被拒绝
+func TestCrontabMutationsRequireAdmin(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	h := &CrontabHandler{}
+	r := gin.New()
+	r.Use(func(c *gin.Context) {
+		c.Set(middleware.ContextIsAdmin, int8(0))
+		c.Next()
+	})
+	r.POST("/crontabs", h.Create)
+	r.PUT("/crontabs/:id", h.Update)
+	r.DELETE("/crontabs/:id", h.Delete)
+	r.POST("/crontabs/:id/run", h.Run)
+	r.PUT("/crontabs/:id/pause", h.Pause)
+	r.PUT("/crontabs/:id/resume", h.Resume)
+
+	for _, route := range []struct{ method, path string }{
+		{http.MethodPost, "/crontabs"},
+		{http.MethodPut, "/crontabs/1"},
+		{http.MethodDelete, "/crontabs/1"},
+		{http.MethodPost, "/crontabs/1/run"},
+		{http.MethodPut, "/crontabs/1/pause"},
+		{http.MethodPut, "/crontabs/1/resume"},
+	} {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(route.method, route.path, nil))
+		if w.Code != http.StatusForbidden {
+			t.Errorf("%s %s: status = %d, want %d", route.method, route.path, w.Code, http.StatusForbidden)
+		}
+	}
+}
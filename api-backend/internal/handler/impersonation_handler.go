@@ -0,0 +1,65 @@
+package handler
+
+import (
+	"adcms/internal/middleware"
+	"adcms/pkg/session"
+	"adcms/pkg/utils"
+	"fmt"
+
+	"github.com/gin-gonic/gin"
+)
+
+// impersonatorName 代登录时返回超管用户名
+func (h *AuthHandler) impersonatorName(c *gin.Context) string {
+	impersonatorID := middleware.GetImpersonatorID(c)
+	if impersonatorID == 0 {
+		return ""
+	}
+	admin, err := h.userRepo.FindByID(impersonatorID)
+	if err != nil {
+		return ""
+	}
+	return admin.Username
+}
+
+// ExitImpersonation 结束代登录，注销代登录会话并签发超管自己会话的 access token
+// @Summary 结束代登录
+// @Tags 认证
+// @Produce json
+// @Success 200 {object} LoginResponse
+// @Router /auth/impersonation/exit [post]
+func (h *AuthHandler) ExitImpersonation(c *gin.Context) {
+	impersonatorID := middleware.GetImpersonatorID(c)
+	if impersonatorID == 0 {
+		utils.Fail(c, 4003, "当前不是代登录状态")
+		return
+	}
+	middleware.OmitResponseLog(c)
+
+	session.Revoke(middleware.GetSessionID(c))
+
+	admin, err := h.userRepo.FindByID(impersonatorID)
+	if err != nil || admin.Status != 1 {
+		utils.Unauthorized(c, "登录已失效，请重新登录")
+		return
+	}
+	adminSession := middleware.GetImpersonatorSession(c)
+	if _, err := session.Get(adminSession); err != nil {
+		utils.Unauthorized(c, "登录已失效，请重新登录")
+		return
+	}
+
+	token, err := utils.GenerateToken(admin.ID, admin.TenantID, admin.Username, admin.IsAdmin, admin.TokenVersion, adminSession)
+	if err != nil {
+		utils.ServerError(c, "生成token失败")
+		return
+	}
+
+	h.recordLoginLog(admin.TenantID, admin.ID, admin.Username, c.ClientIP(), c.Request.UserAgent(), 1,
+		fmt.Sprintf("结束代登录[%s]", middleware.GetUsername(c)))
+
+	utils.Success(c, LoginResponse{
+		Token:     token,
+		ExpiresIn: int64(utils.AccessTokenTTL().Seconds()),
+	})
+}
@@ -0,0 +1,56 @@
+package handler
+
+import (
+	"adcms/internal/middleware"
+	"adcms/pkg/jwtkeys"
+	"adcms/pkg/utils"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+type JWTKeyHandler struct{}
+
+func NewJWTKeyHandler() *JWTKeyHandler {
+	return &JWTKeyHandler{}
+}
+
+// JWKS 公开验签公钥，供其他服务校验本系统签发的 token
+func (h *JWTKeyHandler) JWKS(c *gin.Context) {
+	c.Header("Cache-Control", "public, max-age=300")
+	c.JSON(http.StatusOK, gin.H{"keys": jwtkeys.JWKS()})
+}
+
+// List 查看签名密钥（不含私钥）
+func (h *JWTKeyHandler) List(c *gin.Context) {
+	if !middleware.IsSuperAdmin(middleware.GetUserID(c)) {
+		utils.Fail(c, 4003, "仅超级管理员可操作")
+		return
+	}
+
+	keys, err := jwtkeys.List()
+	if err != nil {
+		utils.ServerError(c, "查询失败")
+		return
+	}
+	utils.Success(c, keys)
+}
+
+// Rotate 轮换签名密钥，旧密钥在其签发的 token 过期前仍可验签，不影响已登录用户
+func (h *JWTKeyHandler) Rotate(c *gin.Context) {
+	if !middleware.IsSuperAdmin(middleware.GetUserID(c)) {
+		utils.Fail(c, 4003, "仅超级管理员可操作")
+		return
+	}
+	if !jwtkeys.Enabled() {
+		utils.Fail(c, 1016, "当前使用HS256签名，请先配置jwt.algorithm为RS256或EdDSA")
+		return
+	}
+
+	key, err := jwtkeys.Rotate()
+	if err != nil {
+		utils.ServerError(c, "轮换失败: "+err.Error())
+		return
+	}
+	utils.SuccessWithMessage(c, "签名密钥已轮换", key)
+}
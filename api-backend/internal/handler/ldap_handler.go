@@ -0,0 +1,228 @@
+package handler
+
+import (
+	"adcms/internal/middleware"
+	"adcms/internal/model"
+	"adcms/internal/repository"
+	"adcms/pkg/ldapauth"
+	"adcms/pkg/utils"
+	"encoding/json"
+	"fmt"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// LDAPHandler 租户 LDAP / AD 目录配置
+type LDAPHandler struct {
+	ldapRepo *repository.LDAPRepository
+	roleRepo *repository.RoleRepository
+}
+
+func NewLDAPHandler() *LDAPHandler {
+	return &LDAPHandler{
+		ldapRepo: repository.NewLDAPRepository(),
+		roleRepo: repository.NewRoleRepository(),
+	}
+}
+
+type LDAPConfigRequest struct {
+	Name               string                `json:"name" binding:"required"`
+	URL                string                `json:"url" binding:"required"`
+	StartTLS           int8                  `json:"start_tls"`
+	InsecureSkipVerify int8                  `json:"insecure_skip_verify"`
+	BindDN             string                `json:"bind_dn"`
+	BindPassword       string                `json:"bind_password"` // 修改时为空表示不变
+	BaseDN             string                `json:"base_dn" binding:"required"`
+	UserFilter         string                `json:"user_filter"`
+	UsernameAttr       string                `json:"username_attr"`
+	EmailAttr          string                `json:"email_attr"`
+	NicknameAttr       string                `json:"nickname_attr"`
+	PhoneAttr          string                `json:"phone_attr"`
+	GroupAttr          string                `json:"group_attr"`
+	DisabledFilter     string                `json:"disabled_filter"`
+	GroupRoles         []model.LDAPGroupRole `json:"group_roles"`
+	AutoCreate         int8                  `json:"auto_create"`
+	DefaultRoleID      uint                  `json:"default_role_id"`
+	Status             int8                  `json:"status"`
+}
+
+// validate 校验目录配置，以及映射的角色属于本租户且操作者有权分配
+func (h *LDAPHandler) validate(c *gin.Context, cfg *model.LDAPConfig, req *LDAPConfigRequest) bool {
+	if err := ldapauth.Validate(cfg); err != nil {
+		utils.BadRequest(c, err.Error())
+		return false
+	}
+
+	var roleIDs []uint
+	for _, m := range req.GroupRoles {
+		if m.Group == "" || m.RoleID == 0 {
+			utils.BadRequest(c, "分组和角色不能为空")
+			return false
+		}
+		roleIDs = append(roleIDs, m.RoleID)
+	}
+	if req.DefaultRoleID > 0 {
+		roleIDs = append(roleIDs, req.DefaultRoleID)
+	}
+	if len(roleIDs) == 0 {
+		return true
+	}
+	for _, id := range roleIDs {
+		role, err := h.roleRepo.FindByID(id)
+		if err != nil || role.TenantID != middleware.GetTenantID(c) {
+			utils.BadRequest(c, fmt.Sprintf("角色%d不存在", id))
+			return false
+		}
+	}
+	if !middleware.CanAssignRoles(middleware.GetUserID(c), roleIDs) {
+		utils.Fail(c, 4003, "无权分配该角色，不能分配与自己同级或更高级别的角色")
+		return false
+	}
+	return true
+}
+
+func (h *LDAPHandler) fill(cfg *model.LDAPConfig, req *LDAPConfigRequest) {
+	cfg.Name = req.Name
+	cfg.URL = req.URL
+	cfg.StartTLS = req.StartTLS
+	cfg.InsecureSkipVerify = req.InsecureSkipVerify
+	cfg.BindDN = req.BindDN
+	if req.BindPassword != "" {
+		cfg.BindPassword = req.BindPassword
+	}
+	cfg.BaseDN = req.BaseDN
+	cfg.UserFilter = req.UserFilter
+	cfg.UsernameAttr = req.UsernameAttr
+	cfg.EmailAttr = req.EmailAttr
+	cfg.NicknameAttr = req.NicknameAttr
+	cfg.PhoneAttr = req.PhoneAttr
+	cfg.GroupAttr = req.GroupAttr
+	cfg.DisabledFilter = req.DisabledFilter
+	cfg.GroupRoles = ""
+	if len(req.GroupRoles) > 0 {
+		data, _ := json.Marshal(req.GroupRoles)
+		cfg.GroupRoles = string(data)
+	}
+	cfg.AutoCreate = req.AutoCreate
+	cfg.DefaultRoleID = req.DefaultRoleID
+	cfg.Status = req.Status
+}
+
+// findConfig 查找本租户的目录配置
+func (h *LDAPHandler) findConfig(c *gin.Context) (*model.LDAPConfig, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		utils.BadRequest(c, "参数错误")
+		return nil, false
+	}
+	cfg, err := h.ldapRepo.FindByID(uint(id))
+	if err != nil || cfg.TenantID != middleware.GetTenantID(c) {
+		utils.Fail(c, 404, "目录配置不存在")
+		return nil, false
+	}
+	return cfg, true
+}
+
+func (h *LDAPHandler) List(c *gin.Context) {
+	configs, err := h.ldapRepo.List(middleware.GetTenantID(c))
+	if err != nil {
+		utils.ServerError(c, "查询失败")
+		return
+	}
+	utils.Success(c, configs)
+}
+
+func (h *LDAPHandler) Create(c *gin.Context) {
+	// 请求中含服务账号密码，不写入操作日志
+	middleware.OmitRequestLog(c)
+
+	if middleware.GetIsAdmin(c) == 0 {
+		utils.Forbidden(c, "仅管理员可配置目录登录")
+		return
+	}
+
+	var req LDAPConfigRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.BadRequest(c, "参数错误")
+		return
+	}
+
+	cfg := model.LDAPConfig{TenantBaseModel: model.TenantBaseModel{TenantID: middleware.GetTenantID(c)}}
+	h.fill(&cfg, &req)
+	if !h.validate(c, &cfg, &req) {
+		return
+	}
+	if err := h.ldapRepo.Create(&cfg); err != nil {
+		utils.ServerError(c, "创建失败")
+		return
+	}
+	utils.SuccessWithMessage(c, "创建成功", cfg)
+}
+
+func (h *LDAPHandler) Update(c *gin.Context) {
+	// 请求中含服务账号密码，不写入操作日志
+	middleware.OmitRequestLog(c)
+
+	if middleware.GetIsAdmin(c) == 0 {
+		utils.Forbidden(c, "仅管理员可配置目录登录")
+		return
+	}
+
+	cfg, ok := h.findConfig(c)
+	if !ok {
+		return
+	}
+
+	var req LDAPConfigRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.BadRequest(c, "参数错误")
+		return
+	}
+
+	h.fill(cfg, &req)
+	if !h.validate(c, cfg, &req) {
+		return
+	}
+	if err := h.ldapRepo.Update(cfg); err != nil {
+		utils.ServerError(c, "更新失败")
+		return
+	}
+	utils.SuccessWithMessage(c, "更新成功", cfg)
+}
+
+func (h *LDAPHandler) Delete(c *gin.Context) {
+	if middleware.GetIsAdmin(c) == 0 {
+		utils.Forbidden(c, "仅管理员可配置目录登录")
+		return
+	}
+
+	cfg, ok := h.findConfig(c)
+	if !ok {
+		return
+	}
+	if err := h.ldapRepo.Delete(cfg.ID); err != nil {
+		utils.ServerError(c, "删除失败")
+		return
+	}
+	utils.SuccessWithMessage(c, "删除成功", nil)
+}
+
+// Test 测试目录连接与服务账号绑定
+// 目录服务器通常在内网，连接错误会原样返回，可被用来探测内网端口，仅超级管理员可用
+func (h *LDAPHandler) Test(c *gin.Context) {
+	if middleware.GetIsAdmin(c) != 2 {
+		utils.Forbidden(c, "仅超级管理员可测试目录连接")
+		return
+	}
+
+	cfg, ok := h.findConfig(c)
+	if !ok {
+		return
+	}
+	if err := ldapauth.Ping(cfg); err != nil {
+		utils.Fail(c, 1013, err.Error())
+		return
+	}
+	utils.SuccessWithMessage(c, "连接成功", nil)
+}
@@ -0,0 +1,217 @@
+package handler
+
+import (
+	"adcms/internal/config"
+	"adcms/internal/model"
+	"adcms/internal/repository"
+	"adcms/pkg/database"
+	"adcms/pkg/email"
+	"adcms/pkg/iplocate"
+	"adcms/pkg/session"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"html"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 新设备 / 异地登录提醒：登录成功时与最近的成功登录记录比对设备特征和归属地，
+// 出现新设备或新地点时发送站内通知和邮件，邮件附带"不是我本人"链接，可注销全部登录
+const (
+	loginAlertHistory   = 20
+	loginAlertTokenTTL  = 7 * 24 * time.Hour
+	loginAlertKeyPrefix = "login_alert:"
+)
+
+var (
+	uaVersionPattern  = regexp.MustCompile(`[\d._]+`)
+	alertTokenPattern = regexp.MustCompile(`^[0-9a-f]{64}$`)
+)
+
+// deviceFingerprint 去掉版本号后的 UA，浏览器或系统升级不视为新设备
+func deviceFingerprint(ua string) string {
+	return strings.Join(strings.Fields(uaVersionPattern.ReplaceAllString(strings.ToLower(ua), " ")), " ")
+}
+
+// deviceName 从 UA 中提取浏览器和系统名称，用于提醒内容
+func deviceName(ua string) string {
+	lower := strings.ToLower(ua)
+	os := "未知系统"
+	for _, item := range []struct{ key, name string }{
+		{"windows", "Windows"}, {"iphone", "iPhone"}, {"ipad", "iPad"}, {"android", "Android"},
+		{"mac os", "macOS"}, {"linux", "Linux"},
+	} {
+		if strings.Contains(lower, item.key) {
+			os = item.name
+			break
+		}
+	}
+	browser := "未知浏览器"
+	for _, item := range []struct{ key, name string }{
+		{"micromessenger", "微信"}, {"edg/", "Edge"}, {"firefox", "Firefox"}, {"chrome", "Chrome"},
+		{"safari", "Safari"}, {"curl", "curl"},
+	} {
+		if strings.Contains(lower, item.key) {
+			browser = item.name
+			break
+		}
+	}
+	return browser + " / " + os
+}
+
+// checkLoginAlert 登录成功时检查是否为新设备或新地点，需在记录本次登录日志之前调用
+// 没有历史登录记录（首次登录或未开启登录日志）时不提醒
+func (h *AuthHandler) checkLoginAlert(c *gin.Context, user *model.User) {
+	ip, ua := c.ClientIP(), c.Request.UserAgent()
+
+	var logs []model.LoginLog
+	database.DB.Select("ip", "user_agent").Where("user_id = ? AND status = 1", user.ID).
+		Order("id DESC").Limit(loginAlertHistory).Find(&logs)
+	if len(logs) == 0 {
+		return
+	}
+
+	fingerprint := deviceFingerprint(ua)
+	region := iplocate.Lookup(ip)
+	knownDevice, knownPlace := false, false
+	for _, l := range logs {
+		if deviceFingerprint(l.UserAgent) == fingerprint {
+			knownDevice = true
+		}
+		if l.IP == ip {
+			knownPlace = true
+		} else if region.Known() {
+			prev := iplocate.Lookup(l.IP)
+			if prev.Internal == region.Internal && prev.Adcode == region.Adcode {
+				knownPlace = true
+			}
+		}
+	}
+	if knownDevice && knownPlace {
+		return
+	}
+
+	// 链接只使用配置的站点公开地址，请求中的 Host 可被登录者伪造
+	baseURL := strings.TrimRight(config.GlobalConfig.Server.PublicURL, "/")
+	go sendLoginAlert(*user, ip, region.String(), deviceName(ua), baseURL)
+}
+
+func sendLoginAlert(user model.User, ip, location, device, baseURL string) {
+	now := time.Now()
+	displayLocation := location
+	if displayLocation == "" {
+		displayLocation = "未知"
+	}
+
+	content := fmt.Sprintf("您的账号于 %s 在新的设备或地点登录（IP：%s，地点：%s，设备：%s）。如非本人操作，请立即修改密码并在登录设备中注销可疑会话。",
+		now.Format("2006-01-02 15:04:05"), ip, displayLocation, device)
+	extra, _ := json.Marshal(gin.H{"ip": ip, "location": location, "device": device})
+	notification := model.Notification{
+		TenantBaseModel: model.TenantBaseModel{TenantID: user.TenantID},
+		ReceiverID:      user.ID,
+		Title:           "新设备登录提醒",
+		Content:         content,
+		Type:            "system",
+		Extra:           string(extra),
+	}
+	if err := repository.NewNotificationRepository().Create(&notification); err != nil {
+		fmt.Printf("[LoginAlert] 创建站内通知失败 user=%d err=%v\n", user.ID, err)
+	}
+
+	if user.Email == "" || user.EmailNotify != 1 {
+		return
+	}
+	if baseURL == "" {
+		if err := email.SendLoginAlert(user.Email, user.Username, ip, location, device, now, ""); err != nil {
+			fmt.Printf("[LoginAlert] 发送登录提醒邮件失败 to=%s err=%v\n", user.Email, err)
+		}
+		return
+	}
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		fmt.Printf("[LoginAlert] 生成链接失败: %v\n", err)
+		return
+	}
+	token := hex.EncodeToString(buf)
+	if err := database.RDB.Set(context.Background(), loginAlertKeyPrefix+token, user.ID, loginAlertTokenTTL).Err(); err != nil {
+		fmt.Printf("[LoginAlert] 保存链接失败: %v\n", err)
+		return
+	}
+	revokeURL := baseURL + "/api/auth/login-alert/" + token
+	if err := email.SendLoginAlert(user.Email, user.Username, ip, location, device, now, revokeURL); err != nil {
+		fmt.Printf("[LoginAlert] 发送登录提醒邮件失败 to=%s err=%v\n", user.Email, err)
+	}
+}
+
+func loginAlertPage(c *gin.Context, title, message string, form bool) {
+	action := ""
+	if form {
+		action = `<form method="post"><button type="submit" style="background:#ff4d4f;color:#fff;border:none;padding:10px 24px;border-radius:4px;cursor:pointer;">确认注销全部登录</button></form>`
+	}
+	page := fmt.Sprintf(`<!DOCTYPE html><html><head><meta charset="utf-8"><title>%s</title></head>
+<body><div style="max-width:500px;margin:60px auto;padding:20px;font-family:Arial,sans-serif;">
+<h2 style="color:#1890ff;">%s</h2><p>%s</p>%s</div></body></html>`,
+		html.EscapeString(title), html.EscapeString(title), html.EscapeString(message), action)
+	c.Data(http.StatusOK, "text/html; charset=utf-8", []byte(page))
+}
+
+// LoginAlertConfirm 邮件中"不是我本人"链接的确认页，注销操作需再次提交，避免邮件安全扫描预取链接时误触发
+func (h *AuthHandler) LoginAlertConfirm(c *gin.Context) {
+	token := c.Param("token")
+	if !alertTokenPattern.MatchString(token) || database.RDB.Exists(context.Background(), loginAlertKeyPrefix+token).Val() == 0 {
+		loginAlertPage(c, "链接无效", "链接无效或已过期。如仍怀疑账号被盗用，请通过找回密码重置密码。", false)
+		return
+	}
+	loginAlertPage(c, "不是我本人登录", "确认后将注销该账号在所有设备上的登录、删除全部 API Key 并作废当前密码，之后需通过邮箱或手机找回密码重新设置。", true)
+}
+
+// LoginAlertRevoke 用户确认非本人登录：注销全部会话、删除 API Key 并作废当前密码
+// 登录者已知道当前密码，仅要求下次登录改密会让其抢先设置新密码，因此必须通过邮箱或短信找回密码
+func (h *AuthHandler) LoginAlertRevoke(c *gin.Context) {
+	token := c.Param("token")
+	ctx := context.Background()
+	if !alertTokenPattern.MatchString(token) {
+		loginAlertPage(c, "链接无效", "链接无效或已过期。", false)
+		return
+	}
+	key := loginAlertKeyPrefix + token
+	val, err := database.RDB.Get(ctx, key).Result()
+	if err != nil || database.RDB.Del(ctx, key).Val() == 0 {
+		loginAlertPage(c, "链接无效", "链接无效或已过期。", false)
+		return
+	}
+	userID, _ := strconv.ParseUint(val, 10, 64)
+	user, err := h.userRepo.FindByID(uint(userID))
+	if err != nil {
+		loginAlertPage(c, "链接无效", "账号不存在。", false)
+		return
+	}
+
+	session.RevokeUser(user.ID, "")
+	session.BumpVersion(user.ID)
+	// 登录者可能已借会话创建 API Key，一并删除
+	if err := h.apiKeyRepo.DeleteByUser(user.ID); err != nil {
+		fmt.Printf("[LoginAlert] 删除 API Key 失败 user=%d err=%v\n", user.ID, err)
+	}
+	h.recordLoginLog(user.TenantID, user.ID, user.Username, c.ClientIP(), c.Request.UserAgent(), 0, "用户报告非本人登录，已注销全部会话、删除 API Key 并作废密码")
+
+	// 目录账号的密码由 LDAP / AD 管理，本地无法作废
+	if h.userRepo.HasDirectoryIdentity(user.ID) {
+		loginAlertPage(c, "已注销全部登录", "该账号在所有设备上的登录均已失效。该账号使用企业目录密码，请立即联系管理员在目录中修改密码。", false)
+		return
+	}
+	if err := h.userRepo.InvalidatePassword(user.ID); err != nil {
+		fmt.Printf("[LoginAlert] 作废密码失败 user=%d err=%v\n", user.ID, err)
+		loginAlertPage(c, "已注销全部登录", "该账号在所有设备上的登录均已失效，但密码作废失败，请立即通过找回密码重置密码。", false)
+		return
+	}
+	loginAlertPage(c, "已注销全部登录", "该账号在所有设备上的登录均已失效，当前密码已作废，请通过邮箱或手机找回密码重新设置。", false)
+}
@@ -0,0 +1,397 @@
+package handler
+
+import (
+	"adcms/internal/middleware"
+	"adcms/internal/model"
+	"adcms/internal/repository"
+	"adcms/pkg/sso"
+	"adcms/pkg/utils"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// OIDCHandler 租户单点登录（OIDC 身份提供方）配置
+type OIDCHandler struct {
+	oidcRepo *repository.OIDCRepository
+	roleRepo *repository.RoleRepository
+}
+
+func NewOIDCHandler() *OIDCHandler {
+	return &OIDCHandler{
+		oidcRepo: repository.NewOIDCRepository(),
+		roleRepo: repository.NewRoleRepository(),
+	}
+}
+
+type OIDCProviderRequest struct {
+	Name          string `json:"name" binding:"required"`
+	Issuer        string `json:"issuer" binding:"required"`
+	ClientID      string `json:"client_id" binding:"required"`
+	ClientSecret  string `json:"client_secret"` // 修改时为空表示不变
+	RedirectURL   string `json:"redirect_url" binding:"required"`
+	Scopes        string `json:"scopes"`
+	UsernameClaim string `json:"username_claim"`
+	EmailClaim    string `json:"email_claim"`
+	NicknameClaim string `json:"nickname_claim"`
+	PhoneClaim    string `json:"phone_claim"`
+	AutoCreate    int8   `json:"auto_create"`
+	DefaultRoleID uint   `json:"default_role_id"`
+	Status        int8   `json:"status"`
+}
+
+// validate 校验地址格式，以及默认角色属于本租户且操作者有权分配
+// 服务端会请求 Issuer 的 discovery 与公钥地址，非超级管理员只能配置公网 https 地址
+func (h *OIDCHandler) validate(c *gin.Context, req *OIDCProviderRequest) bool {
+	issuer, err := url.Parse(req.Issuer)
+	if err != nil || issuer.Scheme != "https" || issuer.Host == "" {
+		utils.BadRequest(c, "Issuer 必须是 https 地址")
+		return false
+	}
+	if middleware.GetIsAdmin(c) != 2 {
+		if err := utils.CheckPublicHost(issuer.Hostname()); err != nil {
+			utils.BadRequest(c, "Issuer "+err.Error())
+			return false
+		}
+	}
+	redirect, err := url.Parse(req.RedirectURL)
+	if err != nil || (redirect.Scheme != "https" && redirect.Scheme != "http") || redirect.Host == "" {
+		utils.BadRequest(c, "回调地址必须是 http(s) 地址")
+		return false
+	}
+	if req.DefaultRoleID > 0 {
+		role, err := h.roleRepo.FindByID(req.DefaultRoleID)
+		if err != nil || role.TenantID != middleware.GetTenantID(c) {
+			utils.BadRequest(c, "默认角色不存在")
+			return false
+		}
+		if !middleware.CanAssignRoles(middleware.GetUserID(c), []uint{req.DefaultRoleID}) {
+			utils.Fail(c, 4003, "无权分配该角色，不能分配与自己同级或更高级别的角色")
+			return false
+		}
+	}
+	return true
+}
+
+func (h *OIDCHandler) fill(p *model.OIDCProvider, req *OIDCProviderRequest) {
+	p.Name = req.Name
+	p.Issuer = req.Issuer
+	p.ClientID = req.ClientID
+	if req.ClientSecret != "" {
+		p.ClientSecret = req.ClientSecret
+	}
+	p.RedirectURL = req.RedirectURL
+	p.Scopes = req.Scopes
+	p.UsernameClaim = req.UsernameClaim
+	p.EmailClaim = req.EmailClaim
+	p.NicknameClaim = req.NicknameClaim
+	p.PhoneClaim = req.PhoneClaim
+	p.AutoCreate = req.AutoCreate
+	p.DefaultRoleID = req.DefaultRoleID
+	p.Status = req.Status
+}
+
+// findProvider 查找本租户的身份提供方
+func (h *OIDCHandler) findProvider(c *gin.Context) (*model.OIDCProvider, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		utils.BadRequest(c, "参数错误")
+		return nil, false
+	}
+	p, err := h.oidcRepo.FindByID(uint(id))
+	if err != nil || p.TenantID != middleware.GetTenantID(c) {
+		utils.Fail(c, 404, "单点登录配置不存在")
+		return nil, false
+	}
+	return p, true
+}
+
+func (h *OIDCHandler) List(c *gin.Context) {
+	providers, err := h.oidcRepo.List(middleware.GetTenantID(c))
+	if err != nil {
+		utils.ServerError(c, "查询失败")
+		return
+	}
+	utils.Success(c, providers)
+}
+
+func (h *OIDCHandler) Create(c *gin.Context) {
+	// 请求中含客户端密钥，不写入操作日志
+	middleware.OmitRequestLog(c)
+
+	if middleware.GetIsAdmin(c) == 0 {
+		utils.Forbidden(c, "仅管理员可配置单点登录")
+		return
+	}
+
+	var req OIDCProviderRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.BadRequest(c, "参数错误")
+		return
+	}
+	if !h.validate(c, &req) {
+		return
+	}
+
+	p := model.OIDCProvider{TenantBaseModel: model.TenantBaseModel{TenantID: middleware.GetTenantID(c)}}
+	h.fill(&p, &req)
+	if err := h.oidcRepo.Create(&p); err != nil {
+		utils.ServerError(c, "创建失败")
+		return
+	}
+	utils.SuccessWithMessage(c, "创建成功", p)
+}
+
+func (h *OIDCHandler) Update(c *gin.Context) {
+	// 请求中含客户端密钥，不写入操作日志
+	middleware.OmitRequestLog(c)
+
+	if middleware.GetIsAdmin(c) == 0 {
+		utils.Forbidden(c, "仅管理员可配置单点登录")
+		return
+	}
+
+	p, ok := h.findProvider(c)
+	if !ok {
+		return
+	}
+
+	var req OIDCProviderRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.BadRequest(c, "参数错误")
+		return
+	}
+	if !h.validate(c, &req) {
+		return
+	}
+
+	h.fill(p, &req)
+	if err := h.oidcRepo.Update(p); err != nil {
+		utils.ServerError(c, "更新失败")
+		return
+	}
+	utils.SuccessWithMessage(c, "更新成功", p)
+}
+
+func (h *OIDCHandler) Delete(c *gin.Context) {
+	if middleware.GetIsAdmin(c) == 0 {
+		utils.Forbidden(c, "仅管理员可配置单点登录")
+		return
+	}
+
+	p, ok := h.findProvider(c)
+	if !ok {
+		return
+	}
+	if err := h.oidcRepo.Delete(p.ID); err != nil {
+		utils.ServerError(c, "删除失败")
+		return
+	}
+	utils.SuccessWithMessage(c, "删除成功", nil)
+}
+
+type OIDCProviderOption struct {
+	ID   uint   `json:"id"`
+	Name string `json:"name"`
+}
+
+// OIDCProviders 登录页展示的租户单点登录方式
+func (h *AuthHandler) OIDCProviders(c *gin.Context) {
+	tenantID, err := strconv.ParseUint(c.Query("tenant_id"), 10, 64)
+	if err != nil {
+		utils.BadRequest(c, "参数错误")
+		return
+	}
+
+	providers, err := h.oidcRepo.ListEnabled(uint(tenantID))
+	if err != nil {
+		utils.ServerError(c, "查询失败")
+		return
+	}
+	options := make([]OIDCProviderOption, len(providers))
+	for i, p := range providers {
+		options[i] = OIDCProviderOption{ID: p.ID, Name: p.Name}
+	}
+	utils.Success(c, options)
+}
+
+// OIDCAuthorize 生成跳转 IdP 的授权地址，前端跳转后由 IdP 回调到配置的前端回调页
+func (h *AuthHandler) OIDCAuthorize(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		utils.BadRequest(c, "参数错误")
+		return
+	}
+
+	p, err := h.oidcRepo.FindByID(uint(id))
+	if err != nil {
+		utils.Fail(c, 404, "单点登录配置不存在")
+		return
+	}
+
+	authURL, state, err := sso.AuthCodeURL(p)
+	if err != nil {
+		if errors.Is(err, sso.ErrProviderDisabled) {
+			utils.Fail(c, 1019, err.Error())
+			return
+		}
+		utils.Fail(c, 1019, "连接身份提供方失败")
+		return
+	}
+	setOIDCStateCookie(c, state, int(sso.StateTTL.Seconds()))
+	utils.Success(c, gin.H{"auth_url": authURL})
+}
+
+// oidcStateCookieName state 同时以 HttpOnly Cookie 下发给发起登录的浏览器，
+// 回调时必须一致，防止攻击者把自己账号的 code、state 交给受害者完成登录
+const (
+	oidcStateCookieName = "oidc_state"
+	oidcStateCookiePath = "/api/auth/oidc"
+)
+
+func setOIDCStateCookie(c *gin.Context, value string, maxAge int) {
+	secure := c.Request.TLS != nil || c.GetHeader("X-Forwarded-Proto") == "https"
+	c.SetSameSite(http.SameSiteLaxMode)
+	c.SetCookie(oidcStateCookieName, value, maxAge, oidcStateCookiePath, "", secure, true)
+}
+
+type OIDCCallbackRequest struct {
+	Code  string `json:"code" binding:"required"`
+	State string `json:"state" binding:"required"`
+}
+
+// OIDCCallback 前端回调页将 IdP 返回的 code、state 提交至此，完成登录
+// 返回与密码登录相同的 LoginResponse（启用了二次验证的用户同样需要完成第二步）
+func (h *AuthHandler) OIDCCallback(c *gin.Context) {
+	var req OIDCCallbackRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.BadRequest(c, "参数错误")
+		return
+	}
+
+	cookieState, _ := c.Cookie(oidcStateCookieName)
+	setOIDCStateCookie(c, "", -1)
+	if cookieState == "" || subtle.ConstantTimeCompare([]byte(cookieState), []byte(req.State)) != 1 {
+		h.recordLoginLog(0, 0, "", c.ClientIP(), c.Request.UserAgent(), 0, "单点登录失败: state 与发起登录的浏览器不一致")
+		utils.Fail(c, 1019, sso.ErrStateNotFound.Error())
+		return
+	}
+
+	p, ident, err := sso.Exchange(c.Request.Context(), req.State, req.Code, h.oidcRepo.FindByID)
+	if err != nil {
+		fmt.Printf("[SSO] 单点登录失败: %v\n", err)
+		h.recordLoginLog(0, 0, "", c.ClientIP(), c.Request.UserAgent(), 0, "单点登录失败")
+		if errors.Is(err, sso.ErrStateNotFound) || errors.Is(err, sso.ErrProviderDisabled) {
+			utils.Fail(c, 1019, err.Error())
+			return
+		}
+		utils.Fail(c, 1019, "单点登录失败")
+		return
+	}
+
+	user, err := h.oidcUser(p, ident)
+	if err != nil {
+		h.recordLoginLog(p.TenantID, 0, ident.Username, c.ClientIP(), c.Request.UserAgent(), 0, "单点登录失败: "+err.Error())
+		utils.Fail(c, 1019, err.Error())
+		return
+	}
+
+	if !h.checkLoginAllowed(c, user) {
+		return
+	}
+
+	if methods := h.mfaMethods(user); len(methods) > 0 {
+		tempToken, err := utils.GenerateTempToken(user.ID, user.TenantID, user.Username)
+		if err != nil {
+			utils.ServerError(c, "生成token失败")
+			return
+		}
+		utils.Success(c, LoginResponse{
+			TempToken:   tempToken,
+			RequireTotp: user.TOTPEnabled == 1,
+			MFAMethods:  methods,
+		})
+		return
+	}
+
+	resp, err := h.issueTokens(c, user)
+	if err != nil {
+		utils.ServerError(c, "生成token失败")
+		return
+	}
+
+	h.userRepo.UpdateLoginInfo(user.ID, c.ClientIP())
+	h.recordLoginLog(user.TenantID, user.ID, user.Username, c.ClientIP(), c.Request.UserAgent(), 1, fmt.Sprintf("登录成功(单点登录:%s)", p.Name))
+
+	utils.Success(c, resp)
+}
+
+// oidcUser 查找外部身份绑定的本地用户，首次登录时按配置在租户下自动创建
+func (h *AuthHandler) oidcUser(p *model.OIDCProvider, ident *sso.Identity) (*model.User, error) {
+	if bound, err := h.oidcRepo.FindIdentity(p.ID, ident.Subject); err == nil {
+		user, err := h.userRepo.FindByID(bound.UserID)
+		if err != nil || user.TenantID != p.TenantID {
+			return nil, errors.New("绑定的用户不存在")
+		}
+		h.oidcRepo.TouchIdentity(bound.ID)
+		return user, nil
+	}
+
+	if p.AutoCreate != 1 {
+		return nil, errors.New("该账号未开通，请联系管理员")
+	}
+
+	username, err := h.availableUsername(ident.Username, p.TenantID)
+	if err != nil {
+		return nil, err
+	}
+	// 单点登录用户不使用本地密码，设置随机密码
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return nil, err
+	}
+	password, err := utils.HashPassword(hex.EncodeToString(buf))
+	if err != nil {
+		return nil, err
+	}
+
+	user := &model.User{
+		TenantBaseModel: model.TenantBaseModel{TenantID: p.TenantID},
+		Username:        username,
+		Password:        password,
+		Email:           ident.Email,
+		Phone:           ident.Phone,
+		Nickname:        ident.Nickname,
+		Status:          1,
+		Remark:          "单点登录自动创建：" + p.Name,
+	}
+	if err := h.oidcRepo.Provision(user, p.DefaultRoleID, &model.UserIdentity{ProviderID: p.ID, Subject: ident.Subject}); err != nil {
+		return nil, errors.New("创建用户失败")
+	}
+	return user, nil
+}
+
+// availableUsername 用户名全局唯一，与已有用户重名时追加租户ID后缀
+func (h *AuthHandler) availableUsername(base string, tenantID uint) (string, error) {
+	if len([]rune(base)) > 40 {
+		base = string([]rune(base)[:40])
+	}
+	candidates := []string{base, fmt.Sprintf("%s_%d", base, tenantID)}
+	for _, name := range candidates {
+		if _, err := h.userRepo.FindByUsernameGlobal(name); err != nil {
+			return name, nil
+		}
+	}
+	suffix := make([]byte, 3)
+	if _, err := rand.Read(suffix); err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%s_%s", base, hex.EncodeToString(suffix)), nil
+}
@@ -0,0 +1,137 @@
+package handler
+
+import (
+	"adcms/internal/model"
+	"adcms/internal/repository"
+	"adcms/pkg/pwpolicy"
+	"adcms/pkg/session"
+	"adcms/pkg/utils"
+	"fmt"
+
+	"github.com/gin-gonic/gin"
+)
+
+// checkNewPassword 按用户所属租户的密码策略校验新密码，不通过时直接返回错误
+// user 为新建用户时（ID 为 0）不检查历史密码
+func checkNewPassword(c *gin.Context, userRepo *repository.UserRepository, user *model.User, password string) bool {
+	policy := pwpolicy.Load(user.TenantID)
+	if err := policy.Validate(password, user.Username); err != nil {
+		utils.Fail(c, 1014, err.Error())
+		return false
+	}
+	if user.ID == 0 || policy.History == 0 {
+		return true
+	}
+
+	hashes, err := userRepo.RecentPasswordHashes(user.ID, policy.History)
+	if err != nil {
+		utils.ServerError(c, "查询密码历史失败")
+		return false
+	}
+	// 早于密码历史记录的账号，至少不能与当前密码相同
+	hashes = append(hashes, user.Password)
+	if policy.Reused(password, hashes) {
+		utils.Fail(c, 1014, fmt.Sprintf("不能使用最近%d次使用过的密码", policy.History))
+		return false
+	}
+	return true
+}
+
+// passwordExpired 用户是否须先修改密码：被管理员重置过，或超过策略的最长使用天数
+// 目录（LDAP）用户的密码由目录管理，不在此检查
+func (h *AuthHandler) passwordExpired(user *model.User) bool {
+	if user.MustChangePassword != 1 {
+		policy := pwpolicy.Load(user.TenantID)
+		changedAt := user.CreatedAt
+		if user.PasswordChangedAt != nil {
+			changedAt = *user.PasswordChangedAt
+		}
+		if !policy.Expired(changedAt) {
+			return false
+		}
+	}
+	return !h.userRepo.HasDirectoryIdentity(user.ID)
+}
+
+// passwordChangeRequired 密码已过期时不签发登录 token，改为返回仅能用于修改密码的临时 token
+func (h *AuthHandler) passwordChangeRequired(c *gin.Context, user *model.User) bool {
+	if !h.passwordExpired(user) {
+		return false
+	}
+	tempToken, err := utils.GeneratePasswordChangeToken(user.ID, user.TenantID, user.Username)
+	if err != nil {
+		utils.ServerError(c, "生成token失败")
+		return true
+	}
+	h.recordLoginLog(user.TenantID, user.ID, user.Username, c.ClientIP(), c.Request.UserAgent(), 0, "密码已过期，需修改密码")
+	utils.Success(c, LoginResponse{TempToken: tempToken, PasswordExpired: true})
+	return true
+}
+
+type ExpiredPasswordRequest struct {
+	TempToken   string `json:"temp_token" binding:"required"`
+	NewPassword string `json:"new_password" binding:"required"`
+}
+
+// ChangeExpiredPassword 登录时密码已过期，使用临时 token 设置新密码后完成登录
+// @Summary 修改过期密码
+// @Tags 认证
+// @Accept json
+// @Produce json
+// @Param body body ExpiredPasswordRequest true "临时token与新密码"
+// @Success 200 {object} LoginResponse
+// @Router /auth/expired-password [post]
+func (h *AuthHandler) ChangeExpiredPassword(c *gin.Context) {
+	var req ExpiredPasswordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.BadRequest(c, "参数错误")
+		return
+	}
+
+	claims, err := utils.ParseTempToken(req.TempToken)
+	if err != nil || !claims.PasswordChange {
+		utils.Fail(c, 1005, "临时token无效")
+		return
+	}
+
+	user, err := h.userRepo.FindByID(claims.UserID)
+	if err != nil {
+		utils.Fail(c, 1006, "用户不存在")
+		return
+	}
+	if !h.checkLoginAllowed(c, user) {
+		return
+	}
+	// 密码已修改过的临时 token 不能再次使用
+	if !h.passwordExpired(user) {
+		utils.Fail(c, 1005, "临时token无效")
+		return
+	}
+	if !checkNewPassword(c, h.userRepo, user, req.NewPassword) {
+		return
+	}
+
+	hashedPassword, err := utils.HashPassword(req.NewPassword)
+	if err != nil {
+		utils.ServerError(c, "密码加密失败")
+		return
+	}
+	if err := h.userRepo.UpdatePassword(user.ID, hashedPassword); err != nil {
+		utils.ServerError(c, "修改密码失败")
+		return
+	}
+	session.RevokeUser(user.ID, "")
+	session.BumpVersion(user.ID)
+	user.TokenVersion++
+
+	resp, err := h.issueTokens(c, user)
+	if err != nil {
+		utils.ServerError(c, "生成token失败")
+		return
+	}
+
+	h.userRepo.UpdateLoginInfo(user.ID, c.ClientIP())
+	h.recordLoginLog(user.TenantID, user.ID, user.Username, c.ClientIP(), c.Request.UserAgent(), 1, "修改过期密码后登录成功")
+
+	utils.Success(c, resp)
+}
@@ -0,0 +1,40 @@
+package handler
+
+import (
+	"adcms/internal/config"
+	"adcms/internal/middleware"
+	"adcms/pkg/utils"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 密码过期时签发的临时 token 只能用于修改密码，不能访问需要登录的接口
+func TestPasswordChangeTokenRejectedByProtectedRoute(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	config.GlobalConfig = &config.Config{JWT: config.JWTConfig{Secret: "test-secret", ExpireHours: 1}}
+
+	r := gin.New()
+	r.Use(gin.Recovery())
+	r.GET("/api/auth/user-info", middleware.JWTAuth(), func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+
+	pwdChange, err := utils.GeneratePasswordChangeToken(1, 1, "admin")
+	if err != nil {
+		t.Fatalf("GeneratePasswordChangeToken() error = %v", err)
+	}
+	mfa, _ := utils.GenerateTempToken(1, 1, "admin")
+
+	for name, token := range map[string]string{"pwd_change": pwdChange, "mfa": mfa} {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, "/api/auth/user-info", nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		r.ServeHTTP(w, req)
+		if w.Code != http.StatusUnauthorized {
+			t.Errorf("%s token: status = %d, want %d", name, w.Code, http.StatusUnauthorized)
+		}
+	}
+}
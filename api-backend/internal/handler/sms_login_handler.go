@@ -0,0 +1,251 @@
+package handler
+
+import (
+	"adcms/internal/middleware"
+	"adcms/internal/model"
+	"adcms/pkg/captcha"
+	"adcms/pkg/session"
+	"adcms/pkg/sms"
+	"adcms/pkg/utils"
+	"adcms/pkg/verifycode"
+	"errors"
+	"fmt"
+
+	"github.com/gin-gonic/gin"
+)
+
+var (
+	errPhoneNotBound  = errors.New("该手机号未绑定账号")
+	errPhoneAmbiguous = errors.New("该手机号绑定了多个账号，请使用用户名登录")
+)
+
+// checkIssueError 处理验证码发送频率限制等错误，返回是否可以继续
+func checkIssueError(c *gin.Context, err error) bool {
+	if err == nil {
+		return true
+	}
+	if errors.Is(err, verifycode.ErrCooldown) || errors.Is(err, verifycode.ErrDailyLimit) {
+		utils.Fail(c, 1020, err.Error())
+		return false
+	}
+	fmt.Printf("[VerifyCode] 生成验证码失败: %v\n", err)
+	utils.ServerError(c, "发送失败")
+	return false
+}
+
+// findUserByPhone 按手机号查找唯一用户，tenantID 大于 0 时限定租户
+func (h *AuthHandler) findUserByPhone(phone string, tenantID uint) (*model.User, error) {
+	users, err := h.userRepo.ListByPhone(phone)
+	if err != nil {
+		return nil, err
+	}
+	var matched []model.User
+	for _, u := range users {
+		if tenantID == 0 || u.TenantID == tenantID {
+			matched = append(matched, u)
+		}
+	}
+	switch len(matched) {
+	case 0:
+		return nil, errPhoneNotBound
+	case 1:
+		return &matched[0], nil
+	default:
+		return nil, errPhoneAmbiguous
+	}
+}
+
+type PhoneCodeRequest struct {
+	Phone    string `json:"phone" binding:"required"`
+	TenantID uint   `json:"tenant_id"` // 同一手机号绑定多个租户的账号时用于区分
+	CaptchaFields
+}
+
+// sendPhoneCode 向已绑定账号的手机号发送验证码
+// 为防止手机号枚举，未绑定账号时同样占用发送频率并返回成功
+func (h *AuthHandler) sendPhoneCode(c *gin.Context, purpose verifycode.Purpose) {
+	var req PhoneCodeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.BadRequest(c, "请输入手机号")
+		return
+	}
+
+	if !checkCaptcha(c, captcha.SmsCode, req.Phone, req.CaptchaFields) {
+		return
+	}
+	captcha.SmsCode.Record(c.ClientIP(), req.Phone)
+
+	code, err := verifycode.Issue(purpose, req.Phone)
+	if !checkIssueError(c, err) {
+		return
+	}
+
+	if _, err := h.findUserByPhone(req.Phone, req.TenantID); err == nil {
+		go func() {
+			if err := sms.SendVerifyCode(req.Phone, code); err != nil {
+				fmt.Printf("[SMS] 发送验证码失败: %v\n", err)
+			}
+		}()
+	}
+
+	utils.SuccessWithMessage(c, "如果该手机号已绑定账号，验证码将发送到该手机", nil)
+}
+
+// SendLoginSmsCode 发送短信登录验证码
+// @Summary 发送短信登录验证码
+// @Tags 认证
+// @Accept json
+// @Produce json
+// @Param body body PhoneCodeRequest true "手机号"
+// @Router /auth/login-sms/code [post]
+func (h *AuthHandler) SendLoginSmsCode(c *gin.Context) {
+	h.sendPhoneCode(c, verifycode.PurposeLogin)
+}
+
+type SmsLoginRequest struct {
+	Phone    string `json:"phone" binding:"required"`
+	Code     string `json:"code" binding:"required,len=6"`
+	TenantID uint   `json:"tenant_id"`
+}
+
+// LoginBySms 短信验证码登录，启用了两步验证的用户仍需完成第二步
+// @Summary 短信验证码登录
+// @Tags 认证
+// @Accept json
+// @Produce json
+// @Param body body SmsLoginRequest true "手机号与验证码"
+// @Success 200 {object} LoginResponse
+// @Router /auth/login-sms [post]
+func (h *AuthHandler) LoginBySms(c *gin.Context) {
+	var req SmsLoginRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.BadRequest(c, "参数错误")
+		return
+	}
+
+	err := verifycode.Check(verifycode.PurposeLogin, req.Phone, req.Code)
+	if err == nil && !verifycode.Consume(verifycode.PurposeLogin, req.Phone) {
+		err = verifycode.ErrInvalid // 已被并发请求使用
+	}
+	if err != nil {
+		h.recordLoginLog(req.TenantID, 0, req.Phone, c.ClientIP(), c.Request.UserAgent(), 0, "短信验证码错误")
+		utils.Fail(c, 1021, err.Error())
+		return
+	}
+
+	user, err := h.findUserByPhone(req.Phone, req.TenantID)
+	if err != nil {
+		if errors.Is(err, errPhoneNotBound) || errors.Is(err, errPhoneAmbiguous) {
+			utils.Fail(c, 1006, err.Error())
+			return
+		}
+		utils.ServerError(c, "查询用户失败")
+		return
+	}
+
+	if !h.checkLoginAllowed(c, user) {
+		return
+	}
+
+	if methods := h.mfaMethods(user); len(methods) > 0 {
+		tempToken, err := utils.GenerateTempToken(user.ID, user.TenantID, user.Username)
+		if err != nil {
+			utils.ServerError(c, "生成token失败")
+			return
+		}
+		utils.Success(c, LoginResponse{
+			TempToken:   tempToken,
+			RequireTotp: user.TOTPEnabled == 1,
+			MFAMethods:  methods,
+		})
+		return
+	}
+
+	if h.passwordChangeRequired(c, user) {
+		return
+	}
+
+	resp, err := h.issueTokens(c, user)
+	if err != nil {
+		utils.ServerError(c, "生成token失败")
+		return
+	}
+
+	middleware.ClearLoginFail(user.Username, c.ClientIP())
+	h.userRepo.UpdateLoginInfo(user.ID, c.ClientIP())
+	h.recordLoginLog(user.TenantID, user.ID, user.Username, c.ClientIP(), c.Request.UserAgent(), 1, "登录成功(短信)")
+
+	utils.Success(c, resp)
+}
+
+// ForgotPasswordBySms 发送短信找回密码验证码
+// @Summary 短信找回密码
+// @Tags 认证
+// @Accept json
+// @Produce json
+// @Param body body PhoneCodeRequest true "手机号"
+// @Router /auth/forgot-password-sms [post]
+func (h *AuthHandler) ForgotPasswordBySms(c *gin.Context) {
+	h.sendPhoneCode(c, verifycode.PurposeResetPassword)
+}
+
+type ResetPasswordBySmsRequest struct {
+	Phone       string `json:"phone" binding:"required"`
+	Code        string `json:"code" binding:"required,len=6"`
+	NewPassword string `json:"new_password" binding:"required"`
+	TenantID    uint   `json:"tenant_id"`
+}
+
+// ResetPasswordBySms 使用短信验证码重置密码
+// @Summary 短信验证码重置密码
+// @Tags 认证
+// @Accept json
+// @Produce json
+// @Param body body ResetPasswordBySmsRequest true "手机号、验证码与新密码"
+// @Router /auth/reset-password-sms [post]
+func (h *AuthHandler) ResetPasswordBySms(c *gin.Context) {
+	var req ResetPasswordBySmsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.BadRequest(c, "参数错误")
+		return
+	}
+
+	err := verifycode.Check(verifycode.PurposeResetPassword, req.Phone, req.Code)
+	if err == nil && !verifycode.Consume(verifycode.PurposeResetPassword, req.Phone) {
+		err = verifycode.ErrInvalid // 已被并发请求使用
+	}
+	if err != nil {
+		utils.Fail(c, 1021, err.Error())
+		return
+	}
+
+	user, err := h.findUserByPhone(req.Phone, req.TenantID)
+	if err != nil {
+		if errors.Is(err, errPhoneNotBound) || errors.Is(err, errPhoneAmbiguous) {
+			utils.Fail(c, 1006, err.Error())
+			return
+		}
+		utils.ServerError(c, "查询用户失败")
+		return
+	}
+	if !checkNewPassword(c, h.userRepo, user, req.NewPassword) {
+		return
+	}
+
+	hashedPassword, err := utils.HashPassword(req.NewPassword)
+	if err != nil {
+		utils.ServerError(c, "密码加密失败")
+		return
+	}
+	if err := h.userRepo.UpdatePassword(user.ID, hashedPassword); err != nil {
+		utils.ServerError(c, "重置密码失败")
+		return
+	}
+
+	// 密码已重置，注销该用户所有登录会话并删除 API Key
+	session.RevokeUser(user.ID, "")
+	session.BumpVersion(user.ID)
+	h.apiKeyRepo.DeleteByUser(user.ID)
+
+	utils.SuccessWithMessage(c, "密码重置成功", nil)
+}
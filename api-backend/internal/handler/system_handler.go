@@ -8,6 +8,7 @@ import (
 	"adcms/pkg/email"
 	"adcms/pkg/sms"
 	"adcms/pkg/logcfg"
+	"adcms/pkg/pwpolicy"
 	"adcms/pkg/utils"
 	"strconv"
 
@@ -600,19 +601,31 @@ func (h *ConfigHandler) TestSms(c *gin.Context) {
 // 日志配置相关接口
 var logConfigKeys = []string{"log_operation_enabled", "log_login_enabled", "log_email_enabled", "log_sms_enabled"}
 
+// logRetentionKeys 各类日志保留天数，0=永久保留
+var logRetentionKeys = []string{
+	"log_operation_retention_days", "log_login_retention_days", "log_email_retention_days",
+	"log_sms_retention_days", "log_crontab_retention_days",
+}
+
 func (h *ConfigHandler) GetLogConfig(c *gin.Context) {
 	if !middleware.IsSuperAdmin(middleware.GetUserID(c)) {
 		utils.Fail(c, 4003, "仅超级管理员可操作")
 		return
 	}
 
+	keys := append(append([]string{}, logConfigKeys...), logRetentionKeys...)
+	keys = append(keys, "log_archive_enabled")
 	var configs []model.SystemConfig
-	database.DB.Where("`key` IN ?", logConfigKeys).Find(&configs)
+	database.DB.Where("`key` IN ?", keys).Find(&configs)
 
 	result := make(map[string]string)
 	for _, key := range logConfigKeys {
 		result[key] = "1" // 默认启用
 	}
+	for _, key := range logRetentionKeys {
+		result[key] = strconv.Itoa(logcfg.DefaultRetentionDays)
+	}
+	result["log_archive_enabled"] = "0" // 默认不归档
 	for _, cfg := range configs {
 		result[cfg.Key] = cfg.Value
 	}
@@ -624,6 +637,14 @@ type LogConfigRequest struct {
 	LoginEnabled     string `json:"log_login_enabled"`
 	EmailEnabled     string `json:"log_email_enabled"`
 	SmsEnabled       string `json:"log_sms_enabled"`
+
+	// 以下为空时不修改
+	OperationRetentionDays string `json:"log_operation_retention_days"`
+	LoginRetentionDays     string `json:"log_login_retention_days"`
+	EmailRetentionDays     string `json:"log_email_retention_days"`
+	SmsRetentionDays       string `json:"log_sms_retention_days"`
+	CrontabRetentionDays   string `json:"log_crontab_retention_days"`
+	ArchiveEnabled         string `json:"log_archive_enabled"`
 }
 
 func (h *ConfigHandler) UpdateLogConfig(c *gin.Context) {
@@ -645,6 +666,27 @@ func (h *ConfigHandler) UpdateLogConfig(c *gin.Context) {
 		"log_sms_enabled":      req.SmsEnabled,
 	}
 
+	retention := map[string]string{
+		"log_operation_retention_days": req.OperationRetentionDays,
+		"log_login_retention_days":     req.LoginRetentionDays,
+		"log_email_retention_days":     req.EmailRetentionDays,
+		"log_sms_retention_days":       req.SmsRetentionDays,
+		"log_crontab_retention_days":   req.CrontabRetentionDays,
+	}
+	for key, value := range retention {
+		if value == "" {
+			continue
+		}
+		if days, err := strconv.Atoi(value); err != nil || days < 0 {
+			utils.BadRequest(c, "日志保留天数须为非负整数")
+			return
+		}
+		items[key] = value
+	}
+	if req.ArchiveEnabled != "" {
+		items["log_archive_enabled"] = req.ArchiveEnabled
+	}
+
 	for key, value := range items {
 		cfg := model.SystemConfig{
 			TenantID:    0,
@@ -660,3 +702,44 @@ func (h *ConfigHandler) UpdateLogConfig(c *gin.Context) {
 
 	utils.SuccessWithMessage(c, "日志配置已保存", nil)
 }
+
+// 密码策略：管理员配置本租户策略，超级管理员配置全局默认策略（tenant_id=0）
+func (h *ConfigHandler) GetPasswordPolicy(c *gin.Context) {
+	if middleware.GetIsAdmin(c) == 0 {
+		utils.Fail(c, 4003, "仅管理员可操作")
+		return
+	}
+	utils.Success(c, pwpolicy.Load(middleware.GetTenantID(c)))
+}
+
+func (h *ConfigHandler) UpdatePasswordPolicy(c *gin.Context) {
+	if middleware.GetIsAdmin(c) == 0 {
+		utils.Fail(c, 4003, "仅管理员可操作")
+		return
+	}
+
+	var req pwpolicy.Policy
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.BadRequest(c, "参数错误")
+		return
+	}
+	if err := req.Check(); err != nil {
+		utils.BadRequest(c, err.Error())
+		return
+	}
+
+	for key, value := range req.Values() {
+		cfg := model.SystemConfig{
+			TenantID:    middleware.GetTenantID(c),
+			Key:         key,
+			Value:       value,
+			Description: "密码策略",
+		}
+		if err := h.configRepo.Upsert(&cfg); err != nil {
+			utils.ServerError(c, "保存失败")
+			return
+		}
+	}
+
+	utils.SuccessWithMessage(c, "密码策略已保存", req)
+}
@@ -5,8 +5,12 @@ import (
 	"adcms/internal/model"
 	"adcms/internal/repository"
 	"adcms/pkg/database"
+	"adcms/pkg/email"
 	"adcms/pkg/excel"
+	"adcms/pkg/pwpolicy"
+	"adcms/pkg/session"
 	"adcms/pkg/utils"
+	"encoding/json"
 	"fmt"
 	"strconv"
 	"time"
@@ -15,12 +19,14 @@ import (
 )
 
 type UserHandler struct {
-	userRepo *repository.UserRepository
+	userRepo   *repository.UserRepository
+	apiKeyRepo *repository.APIKeyRepository
 }
 
 func NewUserHandler() *UserHandler {
 	return &UserHandler{
-		userRepo: repository.NewUserRepository(),
+		userRepo:   repository.NewUserRepository(),
+		apiKeyRepo: repository.NewAPIKeyRepository(),
 	}
 }
 
@@ -43,6 +49,9 @@ type CreateUserRequest struct {
 }
 
 func (h *UserHandler) Create(c *gin.Context) {
+	// 请求中含明文密码，不写入操作日志
+	middleware.OmitRequestLog(c)
+
 	var req CreateUserRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
 		utils.BadRequest(c, "参数错误")
@@ -65,12 +74,17 @@ func (h *UserHandler) Create(c *gin.Context) {
 		return
 	}
 
+	if !checkNewPassword(c, h.userRepo, &model.User{TenantBaseModel: model.TenantBaseModel{TenantID: tenantID}, Username: req.Username}, req.Password) {
+		return
+	}
+
 	hashedPassword, err := utils.HashPassword(req.Password)
 	if err != nil {
 		utils.ServerError(c, "密码加密失败")
 		return
 	}
 
+	now := time.Now()
 	user := model.User{
 		TenantBaseModel: model.TenantBaseModel{TenantID: tenantID},
 		Username:        req.Username,
@@ -88,6 +102,7 @@ func (h *UserHandler) Create(c *gin.Context) {
 		MaxUsers:        req.MaxUsers,
 		Remark:          req.Remark,
 	}
+	user.PasswordChangedAt = &now
 
 	if err := h.userRepo.Create(&user); err != nil {
 		utils.ServerError(c, "创建用户失败")
@@ -151,6 +166,7 @@ func (h *UserHandler) Update(c *gin.Context) {
 		return
 	}
 
+	statusChanged := user.Status != req.Status
 	user.Email = req.Email
 	user.Phone = req.Phone
 	user.Nickname = req.Nickname
@@ -173,6 +189,14 @@ func (h *UserHandler) Update(c *gin.Context) {
 		h.userRepo.AssignRoles(user.ID, req.RoleIDs)
 	}
 
+	// 状态或角色变化后旧 token 失效
+	if statusChanged || req.RoleIDs != nil {
+		session.BumpVersion(user.ID)
+	}
+	if statusChanged && user.Status != 1 {
+		session.RevokeUser(user.ID, "")
+	}
+
 	utils.Success(c, user)
 }
 
@@ -202,6 +226,8 @@ func (h *UserHandler) Delete(c *gin.Context) {
 		utils.ServerError(c, "删除用户失败")
 		return
 	}
+	session.RevokeUser(targetID, "")
+	session.BumpVersion(targetID)
 
 	utils.SuccessWithMessage(c, "删除成功", nil)
 }
@@ -278,11 +304,24 @@ func (h *UserHandler) ToggleStatus(c *gin.Context) {
 		utils.ServerError(c, "更新状态失败")
 		return
 	}
+	// 禁用/锁定后立即踢下线
+	session.BumpVersion(targetID)
+	if req.Status != 1 {
+		session.RevokeUser(targetID, "")
+	}
 
 	utils.SuccessWithMessage(c, "更新成功", nil)
 }
 
+type ResetPasswordRequest struct {
+	Password string `json:"password"` // 为空时生成随机密码
+}
+
 func (h *UserHandler) ResetPassword(c *gin.Context) {
+	// 请求中可能含新密码，响应中含新密码，均不写入操作日志
+	middleware.OmitRequestLog(c)
+	middleware.OmitResponseLog(c)
+
 	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
 	if err != nil {
 		utils.BadRequest(c, "参数错误")
@@ -298,18 +337,94 @@ func (h *UserHandler) ResetPassword(c *gin.Context) {
 		return
 	}
 
-	hashedPassword, err := utils.HashPassword("123456")
+	user, err := h.userRepo.FindByID(targetID)
+	if err != nil {
+		utils.Fail(c, 3002, "用户不存在")
+		return
+	}
+
+	// 未指定新密码时生成符合密码策略的随机密码
+	var req ResetPasswordRequest
+	_ = c.ShouldBindJSON(&req)
+	password := req.Password
+	if password == "" {
+		if password, err = pwpolicy.Load(user.TenantID).Generate(); err != nil {
+			utils.ServerError(c, "生成密码失败")
+			return
+		}
+	} else if !checkNewPassword(c, h.userRepo, user, password) {
+		return
+	}
+
+	hashedPassword, err := utils.HashPassword(password)
 	if err != nil {
 		utils.ServerError(c, "密码加密失败")
 		return
 	}
 
-	if err := h.userRepo.UpdatePassword(uint(id), hashedPassword); err != nil {
+	// 重置他人密码后，对方下次登录须先修改密码
+	update := h.userRepo.ResetPassword
+	if operatorID == targetID {
+		update = h.userRepo.UpdatePassword
+	}
+	if err := update(targetID, hashedPassword); err != nil {
 		utils.ServerError(c, "重置密码失败")
 		return
 	}
+	session.RevokeUser(targetID, middleware.GetSessionID(c))
+	session.BumpVersion(targetID)
+	h.apiKeyRepo.DeleteByUser(targetID)
 
-	utils.SuccessWithMessage(c, "密码已重置为123456", nil)
+	utils.SuccessWithMessage(c, "密码已重置", gin.H{"password": password})
+}
+
+// Sessions 查看用户已登录的设备
+func (h *UserHandler) Sessions(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		utils.BadRequest(c, "参数错误")
+		return
+	}
+
+	operatorID := middleware.GetUserID(c)
+	if operatorID != uint(id) && !middleware.HasHigherLevel(operatorID, uint(id)) {
+		utils.Fail(c, 4003, "无权查看该用户")
+		return
+	}
+
+	sessions, err := session.List(uint(id))
+	if err != nil {
+		utils.ServerError(c, "查询失败")
+		return
+	}
+	utils.Success(c, sessions)
+}
+
+// RevokeSessions 注销用户的全部登录会话（强制下线）
+func (h *UserHandler) RevokeSessions(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		utils.BadRequest(c, "参数错误")
+		return
+	}
+
+	operatorID := middleware.GetUserID(c)
+	targetID := uint(id)
+	if operatorID == targetID {
+		utils.Fail(c, 4003, "不能强制下线自己，请使用退出登录")
+		return
+	}
+	if !middleware.HasHigherLevel(operatorID, targetID) {
+		utils.Fail(c, 4003, "无权操作该用户")
+		return
+	}
+
+	if err := session.RevokeUser(targetID, ""); err != nil {
+		utils.ServerError(c, "操作失败")
+		return
+	}
+	session.BumpVersion(targetID)
+	utils.SuccessWithMessage(c, "已强制下线", nil)
 }
 
 type AssignRolesRequest struct {
@@ -348,6 +463,7 @@ func (h *UserHandler) AssignRoles(c *gin.Context) {
 		utils.ServerError(c, "分配角色失败")
 		return
 	}
+	session.BumpVersion(uint(id))
 
 	utils.SuccessWithMessage(c, "分配成功", nil)
 }
@@ -385,39 +501,97 @@ func (h *UserHandler) Export(c *gin.Context) {
 	excel.Export(c, "用户列表.xlsx", "用户", userExcelColumns, users)
 }
 
+// userImportColumns 导入模板列，初始密码为空时生成随机密码
+var userImportColumns = []excel.ColumnDef{
+	{Header: "用户名", Field: "Username", Width: 15},
+	{Header: "昵称", Field: "Nickname", Width: 15},
+	{Header: "邮箱", Field: "Email", Width: 25},
+	{Header: "手机", Field: "Phone", Width: 15},
+	{Header: "初始密码", Field: "Password", Width: 20},
+}
+
 func (h *UserHandler) ImportTemplate(c *gin.Context) {
-	excel.ExportTemplate(c, "用户导入模板.xlsx", "用户", userExcelColumns)
+	excel.ExportTemplate(c, "用户导入模板.xlsx", "用户", userImportColumns)
+}
+
+// ImportFailure 导入失败的行
+type ImportFailure struct {
+	Row      int    `json:"row"`
+	Username string `json:"username"`
+	Reason   string `json:"reason"`
+}
+
+// ImportedPassword 导入时生成的初始密码
+type ImportedPassword struct {
+	Username string `json:"username"`
+	Password string `json:"password"`
 }
 
 func (h *UserHandler) Import(c *gin.Context) {
-	records, err := excel.Import(c, "file", userExcelColumns)
+	records, err := excel.Import(c, "file", userImportColumns)
 	if err != nil {
 		utils.BadRequest(c, err.Error())
 		return
 	}
 
 	tenantID := middleware.GetTenantID(c)
+	policy := pwpolicy.Load(tenantID)
 	imported := 0
-	for _, rec := range records {
+	failures := []ImportFailure{}
+	passwords := []ImportedPassword{}
+	for i, rec := range records {
+		row := i + 2 // 第1行为表头
 		username := rec["Username"]
 		if username == "" {
 			continue
 		}
-		password, _ := utils.HashPassword("123456")
+
+		password := rec["Password"]
+		generated := password == ""
+		if generated {
+			if password, err = policy.Generate(); err != nil {
+				failures = append(failures, ImportFailure{Row: row, Username: username, Reason: "生成密码失败"})
+				continue
+			}
+		} else if err := policy.Validate(password, username); err != nil {
+			failures = append(failures, ImportFailure{Row: row, Username: username, Reason: err.Error()})
+			continue
+		}
+
+		hashedPassword, err := utils.HashPassword(password)
+		if err != nil {
+			failures = append(failures, ImportFailure{Row: row, Username: username, Reason: "密码加密失败"})
+			continue
+		}
+		// 初始密码由管理员分发，用户首次登录须先修改
 		user := model.User{
-			TenantBaseModel: model.TenantBaseModel{TenantID: tenantID},
-			Username:        username,
-			Password:        password,
-			Nickname:        rec["Nickname"],
-			Email:           rec["Email"],
-			Phone:           rec["Phone"],
-			Status:          1,
+			TenantBaseModel:    model.TenantBaseModel{TenantID: tenantID},
+			Username:           username,
+			Password:           hashedPassword,
+			Nickname:           rec["Nickname"],
+			Email:              rec["Email"],
+			Phone:              rec["Phone"],
+			Status:             1,
+			MustChangePassword: 1,
+		}
+		if err := h.userRepo.Create(&user); err != nil {
+			failures = append(failures, ImportFailure{Row: row, Username: username, Reason: "创建用户失败"})
+			continue
 		}
-		if err := h.userRepo.Create(&user); err == nil {
-			imported++
+		imported++
+		if generated {
+			passwords = append(passwords, ImportedPassword{Username: username, Password: password})
 		}
 	}
-	utils.Success(c, map[string]int{"imported": imported, "total": len(records)})
+
+	// 生成的初始密码仅在此时返回一次，不写入操作日志
+	middleware.OmitResponseLog(c)
+	utils.Success(c, gin.H{
+		"imported":  imported,
+		"total":     len(records),
+		"failures":  failures,
+		"passwords": passwords,
+	})
 }
 
 func (h *UserHandler) AssignMenus(c *gin.Context) {
@@ -473,12 +647,91 @@ func (h *UserHandler) UnlockUser(c *gin.Context) {
 	utils.SuccessWithMessage(c, "用户已解锁", nil)
 }
 
+// ResetTOTP 超管重置用户的两步验证（用户丢失手机且无可用恢复码时），操作留痕并邮件通知用户
+func (h *UserHandler) ResetTOTP(c *gin.Context) {
+	operatorID := middleware.GetUserID(c)
+	if !middleware.IsSuperAdmin(operatorID) {
+		utils.Forbidden(c, "仅超级管理员可重置两步验证")
+		return
+	}
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		utils.BadRequest(c, "参数错误")
+		return
+	}
+
+	var req struct {
+		Reason string `json:"reason" binding:"required,max=200"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.BadRequest(c, "请填写重置原因")
+		return
+	}
+
+	user, err := h.userRepo.FindByID(uint(id))
+	if err != nil {
+		utils.Fail(c, 3002, "用户不存在")
+		return
+	}
+
+	if user.TOTPEnabled != 1 {
+		utils.Fail(c, 1009, "该用户未启用TOTP")
+		return
+	}
+
+	if err := h.userRepo.UpdateTOTP(user.ID, 0, ""); err != nil {
+		utils.ServerError(c, "重置失败")
+		return
+	}
+	h.userRepo.DeleteRecoveryCodes(user.ID)
+	session.RevokeUser(user.ID, "")
+	session.BumpVersion(user.ID)
+
+	// 审计记录不受操作日志开关影响
+	now := time.Now()
+	params, _ := json.Marshal(gin.H{"user_id": user.ID, "username": user.Username, "reason": req.Reason})
+	database.DB.Create(&model.OperationLog{
+		TenantID:  user.TenantID,
+		UserID:    operatorID,
+		Module:    "用户管理",
+		Action:    "重置两步验证",
+		Method:    c.Request.Method,
+		Path:      c.Request.URL.Path,
+		Params:    string(params),
+		IP:        c.ClientIP(),
+		UserAgent: c.Request.UserAgent(),
+		CreatedAt: now,
+	})
+
+	if user.Email != "" {
+		go func(to, username, reason string) {
+			if err := email.SendTOTPResetNotice(to, username, reason, now); err != nil {
+				fmt.Printf("[Email] 发送两步验证重置通知失败 to=%s err=%v\n", to, err)
+			}
+		}(user.Email, user.Username, req.Reason)
+	}
+
+	utils.SuccessWithMessage(c, "两步验证已重置", nil)
+}
+
 // LoginAs 超管以指定用户身份登录
+// 代登录 token 带有超管身份，有效期受限且不可刷新，期间的操作日志同时记录超管ID，
+// 通过 POST /auth/impersonation/exit 返回超管自己的会话
 func (h *UserHandler) LoginAs(c *gin.Context) {
 	if !middleware.IsSuperAdmin(middleware.GetUserID(c)) {
 		utils.Forbidden(c, "仅超级管理员可使用此功能")
 		return
 	}
+	if middleware.GetImpersonatorID(c) > 0 {
+		utils.Forbidden(c, "请先结束当前代登录")
+		return
+	}
+	adminSession := middleware.GetSessionID(c)
+	if adminSession == "" {
+		utils.Forbidden(c, "请使用账号登录后操作")
+		return
+	}
 
 	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
 	if err != nil {
@@ -503,7 +756,20 @@ func (h *UserHandler) LoginAs(c *gin.Context) {
 		return
 	}
 
-	token, err := utils.GenerateToken(user.ID, user.TenantID, user.Username, user.IsAdmin)
+	// 代登录同样创建会话，目标用户被禁用或强制下线时随之失效
+	ttl := utils.ImpersonationLifetime()
+	sess := &session.Session{
+		UserID:    user.ID,
+		TenantID:  user.TenantID,
+		IP:        c.ClientIP(),
+		UserAgent: c.Request.UserAgent(),
+	}
+	if _, err := session.Create(sess, ttl); err != nil {
+		utils.ServerError(c, "生成token失败")
+		return
+	}
+	token, err := utils.GenerateImpersonationToken(user.ID, user.TenantID, user.Username, user.IsAdmin, user.TokenVersion,
+		sess.ID, middleware.GetUserID(c), adminSession)
 	if err != nil {
 		utils.ServerError(c, "生成token失败")
 		return
@@ -521,5 +787,9 @@ func (h *UserHandler) LoginAs(c *gin.Context) {
 		Message:   fmt.Sprintf("超管[%s]代登录", adminName),
 	})
 
-	utils.Success(c, gin.H{"token": token})
+	utils.Success(c, gin.H{
+		"token":           token,
+		"expires_in":      int64(ttl.Seconds()),
+		"impersonator_id": middleware.GetUserID(c),
+	})
 }
@@ -0,0 +1,205 @@
+package handler
+
+import (
+	"adcms/internal/middleware"
+	"adcms/pkg/passkey"
+	"adcms/pkg/utils"
+	"errors"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// loadPasskeyUser 加载用户及其已注册的通行密钥
+func (h *AuthHandler) loadPasskeyUser(userID uint) (*passkey.User, error) {
+	user, err := h.userRepo.FindByID(userID)
+	if err != nil {
+		return nil, err
+	}
+	creds, err := h.webauthnRepo.ListByUser(userID)
+	if err != nil {
+		return nil, err
+	}
+	return &passkey.User{User: user, Credentials: creds}, nil
+}
+
+// WebAuthnCredentials 当前用户已注册的通行密钥
+func (h *AuthHandler) WebAuthnCredentials(c *gin.Context) {
+	creds, err := h.webauthnRepo.ListByUser(middleware.GetUserID(c))
+	if err != nil {
+		utils.ServerError(c, "查询失败")
+		return
+	}
+	utils.Success(c, creds)
+}
+
+// WebAuthnRegisterBegin 生成通行密钥注册选项
+func (h *AuthHandler) WebAuthnRegisterBegin(c *gin.Context) {
+	if !passkey.Enabled() {
+		utils.Fail(c, 1017, passkey.ErrDisabled.Error())
+		return
+	}
+
+	user, err := h.loadPasskeyUser(middleware.GetUserID(c))
+	if err != nil {
+		utils.Fail(c, 1006, "用户不存在")
+		return
+	}
+
+	creation, err := passkey.BeginRegistration(user)
+	if err != nil {
+		utils.ServerError(c, "生成注册选项失败")
+		return
+	}
+	utils.Success(c, creation)
+}
+
+// WebAuthnRegisterFinish 校验认证器的注册结果并保存通行密钥
+// 请求体为浏览器 navigator.credentials.create() 返回的凭证 JSON，名称通过 ?name= 传递
+func (h *AuthHandler) WebAuthnRegisterFinish(c *gin.Context) {
+	if !passkey.Enabled() {
+		utils.Fail(c, 1017, passkey.ErrDisabled.Error())
+		return
+	}
+
+	user, err := h.loadPasskeyUser(middleware.GetUserID(c))
+	if err != nil {
+		utils.Fail(c, 1006, "用户不存在")
+		return
+	}
+
+	body, err := c.GetRawData()
+	if err != nil {
+		utils.BadRequest(c, "参数错误")
+		return
+	}
+
+	cred, err := passkey.FinishRegistration(user, body)
+	if err != nil {
+		if errors.Is(err, passkey.ErrSessionNotFound) {
+			utils.Fail(c, 1018, err.Error())
+			return
+		}
+		utils.Fail(c, 1018, "通行密钥注册失败")
+		return
+	}
+
+	cred.Name = c.Query("name")
+	if cred.Name == "" {
+		cred.Name = "通行密钥"
+	}
+	if err := h.webauthnRepo.Create(cred); err != nil {
+		utils.ServerError(c, "保存失败")
+		return
+	}
+	utils.SuccessWithMessage(c, "注册成功", cred)
+}
+
+// DeleteWebAuthnCredential 删除当前用户的通行密钥
+func (h *AuthHandler) DeleteWebAuthnCredential(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		utils.BadRequest(c, "参数错误")
+		return
+	}
+
+	ok, err := h.webauthnRepo.Delete(uint(id), middleware.GetUserID(c))
+	if err != nil {
+		utils.ServerError(c, "删除失败")
+		return
+	}
+	if !ok {
+		utils.Fail(c, 404, "通行密钥不存在")
+		return
+	}
+	utils.SuccessWithMessage(c, "删除成功", nil)
+}
+
+type WebAuthnLoginBeginRequest struct {
+	TempToken string `json:"temp_token"` // 密码登录后的临时 token；为空表示无密码登录
+}
+
+// WebAuthnLoginBegin 生成通行密钥登录选项
+// 携带 temp_token 时作为密码登录后的第二步验证，否则发起无密码登录
+func (h *AuthHandler) WebAuthnLoginBegin(c *gin.Context) {
+	if !passkey.Enabled() {
+		utils.Fail(c, 1017, passkey.ErrDisabled.Error())
+		return
+	}
+
+	var req WebAuthnLoginBeginRequest
+	_ = c.ShouldBindJSON(&req)
+
+	var user *passkey.User
+	if req.TempToken != "" {
+		claims, err := utils.ParseTempToken(req.TempToken)
+		if err != nil || !claims.RequireOTP {
+			utils.Fail(c, 1005, "临时token无效")
+			return
+		}
+		if user, err = h.loadPasskeyUser(claims.UserID); err != nil {
+			utils.Fail(c, 1006, "用户不存在")
+			return
+		}
+		if len(user.Credentials) == 0 {
+			utils.Fail(c, 1017, "未注册通行密钥")
+			return
+		}
+	}
+
+	assertion, err := passkey.BeginLogin(user)
+	if err != nil {
+		utils.ServerError(c, "生成登录选项失败")
+		return
+	}
+	utils.Success(c, assertion)
+}
+
+// WebAuthnLoginFinish 校验通行密钥断言并签发 token
+// 请求体为浏览器 navigator.credentials.get() 返回的凭证 JSON
+func (h *AuthHandler) WebAuthnLoginFinish(c *gin.Context) {
+	if !passkey.Enabled() {
+		utils.Fail(c, 1017, passkey.ErrDisabled.Error())
+		return
+	}
+
+	body, err := c.GetRawData()
+	if err != nil {
+		utils.BadRequest(c, "参数错误")
+		return
+	}
+
+	pu, cred, err := passkey.FinishLogin(body, h.loadPasskeyUser)
+	if err != nil {
+		h.recordLoginLog(0, 0, "", c.ClientIP(), c.Request.UserAgent(), 0, "通行密钥验证失败")
+		if errors.Is(err, passkey.ErrSessionNotFound) || errors.Is(err, passkey.ErrCloned) {
+			utils.Fail(c, 1018, err.Error())
+			return
+		}
+		utils.Fail(c, 1018, "通行密钥验证失败")
+		return
+	}
+	user := pu.User
+
+	if !h.checkLoginAllowed(c, user) {
+		return
+	}
+
+	h.webauthnRepo.UpdateUsage(cred)
+
+	if h.passwordChangeRequired(c, user) {
+		return
+	}
+
+	resp, err := h.issueTokens(c, user)
+	if err != nil {
+		utils.ServerError(c, "生成token失败")
+		return
+	}
+
+	middleware.ClearLoginFail(user.Username, c.ClientIP())
+	h.userRepo.UpdateLoginInfo(user.ID, c.ClientIP())
+	h.recordLoginLog(user.TenantID, user.ID, user.Username, c.ClientIP(), c.Request.UserAgent(), 1, "登录成功(通行密钥)")
+
+	utils.Success(c, resp)
+}
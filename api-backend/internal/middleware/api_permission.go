@@ -90,6 +90,12 @@ func RequirePermission(code string) gin.HandlerFunc {
 			return
 		}
 
+		if !HasAPIScope(c, code) {
+			utils.Fail(c, 4003, "API Key 未授权: "+code)
+			c.Abort()
+			return
+		}
+
 		// super_admin 跳过权限检查
 		if IsSuperAdmin(userID) {
 			c.Next()
@@ -119,19 +125,16 @@ func RequireAnyPermission(codes ...string) gin.HandlerFunc {
 			return
 		}
 
-		if IsSuperAdmin(userID) {
-			c.Next()
-			return
-		}
-
-		userCodes := GetUserPermissionCodes(userID)
-		userCodeMap := make(map[string]bool, len(userCodes))
-		for _, uc := range userCodes {
-			userCodeMap[uc] = true
+		superAdmin := IsSuperAdmin(userID)
+		userCodeMap := make(map[string]bool)
+		if !superAdmin {
+			for _, uc := range GetUserPermissionCodes(userID) {
+				userCodeMap[uc] = true
+			}
 		}
 
 		for _, code := range codes {
-			if userCodeMap[code] {
+			if (superAdmin || userCodeMap[code]) && HasAPIScope(c, code) {
 				c.Next()
 				return
 			}
@@ -144,6 +147,7 @@ func RequireAnyPermission(codes ...string) gin.HandlerFunc {
 
 // APIPermissionCheck 基于 path+method 的自动权限检查中间件
 // 挂载到 protected 路由组，自动匹配 permissions 表中的 path+method
+// 通过 API Key 认证的请求只能访问已注册且在 Key 授权范围内的接口（超管同样受限）
 func APIPermissionCheck() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		userID := GetUserID(c)
@@ -153,8 +157,10 @@ func APIPermissionCheck() gin.HandlerFunc {
 			return
 		}
 
+		apiKey := IsAPIKey(c)
+
 		// super_admin 跳过
-		if IsSuperAdmin(userID) {
+		if !apiKey && IsSuperAdmin(userID) {
 			c.Next()
 			return
 		}
@@ -169,6 +175,11 @@ func APIPermissionCheck() gin.HandlerFunc {
 			Count(&count)
 
 		if count == 0 {
+			if apiKey {
+				utils.Fail(c, 4003, "API Key 无权访问该接口")
+				c.Abort()
+				return
+			}
 			// 该接口未注册权限，默认放行（仅认证即可访问）
 			c.Next()
 			return
@@ -194,11 +205,28 @@ func APIPermissionCheck() gin.HandlerFunc {
 		}
 
 		if !matched {
+			if apiKey {
+				utils.Fail(c, 4003, "API Key 无权访问该接口")
+				c.Abort()
+				return
+			}
 			// 该具体路径未注册权限，放行
 			c.Next()
 			return
 		}
 
+		if apiKey {
+			if !HasAPIScope(c, matchedCode) {
+				utils.Fail(c, 4003, "API Key 未授权: "+matchedCode)
+				c.Abort()
+				return
+			}
+			if IsSuperAdmin(userID) {
+				c.Next()
+				return
+			}
+		}
+
 		// 检查用户是否拥有该权限
 		codes := GetUserPermissionCodes(userID)
 		for _, code := range codes {
@@ -2,6 +2,7 @@ package middleware
 
 import (
 	"adcms/pkg/database"
+	"adcms/pkg/session"
 	"adcms/pkg/utils"
 	"context"
 	"strings"
@@ -15,10 +16,23 @@ const (
 	ContextTenantID = "tenant_id"
 	ContextUsername = "username"
 	ContextIsAdmin  = "is_admin"
+	ContextSession  = "session_id"
+	ContextAuthTime = "auth_time"
+
+	ContextImpersonatorID      = "impersonator_id"
+	ContextImpersonatorSession = "impersonator_session_id"
 )
 
+// JWTAuth 校验 access token；请求携带 X-API-Key 时改为按 API Key 认证
 func JWTAuth() gin.HandlerFunc {
 	return func(c *gin.Context) {
+		if apiKey := c.GetHeader(APIKeyHeader); apiKey != "" {
+			if authenticateAPIKey(c, apiKey) {
+				c.Next()
+			}
+			return
+		}
+
 		authHeader := c.GetHeader("Authorization")
 		if authHeader == "" {
 			utils.Unauthorized(c, "请先登录")
@@ -35,6 +49,14 @@ func JWTAuth() gin.HandlerFunc {
 
 		token := parts[1]
 
+		// 仅接受 access token，两步验证、修改密码等临时 token 不能访问接口
+		claims, err := utils.ParseToken(token)
+		if err != nil || claims.ID == "" {
+			utils.Unauthorized(c, "token无效或已过期")
+			c.Abort()
+			return
+		}
+
 		blacklisted, err := isTokenBlacklisted(token)
 		if err != nil || blacklisted {
 			utils.Unauthorized(c, "token已失效")
@@ -42,9 +64,27 @@ func JWTAuth() gin.HandlerFunc {
 			return
 		}
 
-		claims, err := utils.ParseToken(token)
-		if err != nil {
-			utils.Unauthorized(c, "token无效或已过期")
+		// 所属会话已注销（登出、refresh token 重放等）时 access token 一并失效
+		ok, err := session.Touch(claims.ID, c.ClientIP())
+		if err != nil || !ok {
+			utils.Unauthorized(c, "登录已失效，请重新登录")
+			c.Abort()
+			return
+		}
+
+		// 代登录依附于超管自己的会话，超管登出或被强制下线时代登录一并失效
+		if claims.ImpersonatorID > 0 {
+			ok, err := session.Touch(claims.ImpersonatorSession, c.ClientIP())
+			if err != nil || !ok {
+				utils.Unauthorized(c, "代登录已失效，请重新登录")
+				c.Abort()
+				return
+			}
+		}
+
+		// 用户改密、角色变更、禁用后 token 版本递增，旧 token 立即失效
+		if version, err := session.Version(claims.UserID); err != nil || version != claims.Version {
+			utils.Unauthorized(c, "登录状态已变更，请重新登录")
 			c.Abort()
 			return
 		}
@@ -53,6 +93,12 @@ func JWTAuth() gin.HandlerFunc {
 		c.Set(ContextTenantID, claims.TenantID)
 		c.Set(ContextUsername, claims.Username)
 		c.Set(ContextIsAdmin, claims.IsAdmin)
+		c.Set(ContextSession, claims.ID)
+		c.Set(ContextAuthTime, claims.AuthTime)
+		if claims.ImpersonatorID > 0 {
+			c.Set(ContextImpersonatorID, claims.ImpersonatorID)
+			c.Set(ContextImpersonatorSession, claims.ImpersonatorSession)
+		}
 
 		c.Next()
 	}
@@ -105,3 +151,63 @@ func GetIsAdmin(c *gin.Context) int8 {
 	}
 	return isAdmin.(int8)
 }
+
+func GetSessionID(c *gin.Context) string {
+	sessionID, exists := c.Get(ContextSession)
+	if !exists {
+		return ""
+	}
+	return sessionID.(string)
+}
+
+// GetImpersonatorID 代登录时返回超管用户ID，否则返回 0
+func GetImpersonatorID(c *gin.Context) uint {
+	impersonatorID, exists := c.Get(ContextImpersonatorID)
+	if !exists {
+		return 0
+	}
+	return impersonatorID.(uint)
+}
+
+// GetImpersonatorSession 代登录时返回超管自己的会话ID
+func GetImpersonatorSession(c *gin.Context) string {
+	sessionID, exists := c.Get(ContextImpersonatorSession)
+	if !exists {
+		return ""
+	}
+	return sessionID.(string)
+}
+
+// GetAuthTime 最近一次验证密码或两步验证的时间，未验证过（如 refresh 后的 token、API Key）返回零值
+func GetAuthTime(c *gin.Context) time.Time {
+	authTime, exists := c.Get(ContextAuthTime)
+	if !exists || authTime.(int64) == 0 {
+		return time.Time{}
+	}
+	return time.Unix(authTime.(int64), 0)
+}
+
+// RequireRecentAuth 敏感操作要求 maxAge 内验证过身份，否则返回 1024，前端调用 POST /auth/confirm 重新验证后重试
+func RequireRecentAuth(maxAge time.Duration) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		authTime := GetAuthTime(c)
+		if authTime.IsZero() || time.Since(authTime) > maxAge {
+			utils.Fail(c, 1024, "该操作需要重新验证身份")
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
+
+// DenyImpersonation 代登录期间禁止的操作（修改密码、两步验证等账号安全设置）
+func DenyImpersonation() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if GetImpersonatorID(c) > 0 {
+			utils.Forbidden(c, "代登录期间不允许修改账号安全设置")
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
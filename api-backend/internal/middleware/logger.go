@@ -21,6 +21,22 @@ func (w bodyLogWriter) Write(b []byte) (int, error) {
 	return w.ResponseWriter.Write(b)
 }
 
+// contextOmitResponse 标记响应中含敏感信息（如恢复码），操作日志不记录响应内容
+const contextOmitResponse = "log_omit_response"
+
+// OmitResponseLog 当前请求的操作日志不记录响应内容
+func OmitResponseLog(c *gin.Context) {
+	c.Set(contextOmitResponse, true)
+}
+
+// contextOmitRequest 标记请求参数中含敏感信息（如密码、密钥），操作日志不记录请求参数
+const contextOmitRequest = "log_omit_request"
+
+// OmitRequestLog 当前请求的操作日志不记录请求参数
+func OmitRequestLog(c *gin.Context) {
+	c.Set(contextOmitRequest, true)
+}
+
 func OperationLogger() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		startTime := time.Now()
@@ -41,21 +57,31 @@ func OperationLogger() gin.HandlerFunc {
 
 		userID := GetUserID(c)
 		tenantID := GetTenantID(c)
+		impersonatorID := GetImpersonatorID(c)
 
-		if userID > 0 && logcfg.IsLogEnabled("log_operation_enabled") {
+		// 代登录期间的操作用于审计，不受操作日志开关影响
+		if userID > 0 && (impersonatorID > 0 || logcfg.IsLogEnabled("log_operation_enabled")) {
+			response := blw.body.String()
+			if c.GetBool(contextOmitResponse) {
+				response = ""
+			}
+			if c.GetBool(contextOmitRequest) {
+				reqBody = ""
+			}
 			log := model.OperationLog{
-				TenantID:  tenantID,
-				UserID:    userID,
-				Module:    "",
-				Action:    "",
-				Method:    c.Request.Method,
-				Path:      c.Request.URL.Path,
-				Params:    reqBody,
-				Response:  blw.body.String(),
-				IP:        c.ClientIP(),
-				UserAgent: c.Request.UserAgent(),
-				Duration:  duration,
-				CreatedAt: time.Now(),
+				TenantID:       tenantID,
+				UserID:         userID,
+				ImpersonatorID: impersonatorID,
+				Module:         "",
+				Action:         "",
+				Method:         c.Request.Method,
+				Path:           c.Request.URL.Path,
+				Params:         reqBody,
+				Response:       response,
+				IP:             c.ClientIP(),
+				UserAgent:      c.Request.UserAgent(),
+				Duration:       duration,
+				CreatedAt:      time.Now(),
 			}
 
 			go database.DB.Create(&log)
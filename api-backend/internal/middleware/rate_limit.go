@@ -61,17 +61,22 @@ func UserRateLimit(maxRequests int64, window time.Duration) gin.HandlerFunc {
 
 // LoginFailLimit 登录失败次数限制
 // maxAttempts: 最大失败次数, lockDuration: 锁定时长
+// 失败次数与锁定按"用户名 + IP"记录，他人仅凭用户名无法锁定账号；针对单个账号的分布式尝试由图形验证码拦截
 const (
-	LoginMaxAttempts = 5
+	LoginMaxAttempts  = 5
 	LoginLockDuration = 15 * time.Minute
-	loginFailPrefix  = "login:fail:"
-	loginLockPrefix  = "login:lock:"
+	loginFailPrefix   = "login:fail:"
+	loginLockPrefix   = "login:lock:"
 )
 
-// IsLoginLocked 检查账号是否被锁定
-func IsLoginLocked(username string) (bool, int64) {
+func loginKey(prefix, username, ip string) string {
+	return prefix + username + ":" + ip
+}
+
+// IsLoginLocked 检查账号在该 IP 上是否被锁定
+func IsLoginLocked(username, ip string) (bool, int64) {
 	ctx := context.Background()
-	lockKey := loginLockPrefix + username
+	lockKey := loginKey(loginLockPrefix, username, ip)
 
 	ttl, err := database.RDB.TTL(ctx, lockKey).Result()
 	if err != nil || ttl <= 0 {
@@ -83,9 +88,9 @@ func IsLoginLocked(username string) (bool, int64) {
 
 // RecordLoginFail 记录登录失败
 // 返回: 剩余尝试次数, 是否被锁定
-func RecordLoginFail(username string) (int64, bool) {
+func RecordLoginFail(username, ip string) (int64, bool) {
 	ctx := context.Background()
-	failKey := loginFailPrefix + username
+	failKey := loginKey(loginFailPrefix, username, ip)
 
 	count, _ := database.RDB.Incr(ctx, failKey).Result()
 	if count == 1 {
@@ -93,9 +98,8 @@ func RecordLoginFail(username string) (int64, bool) {
 	}
 
 	if count >= int64(LoginMaxAttempts) {
-		// 锁定账号
-		lockKey := loginLockPrefix + username
-		database.RDB.Set(ctx, lockKey, "1", LoginLockDuration)
+		// 锁定该 IP 对此账号的尝试
+		database.RDB.Set(ctx, loginKey(loginLockPrefix, username, ip), "1", LoginLockDuration)
 		database.RDB.Del(ctx, failKey)
 		return 0, true
 	}
@@ -104,11 +108,10 @@ func RecordLoginFail(username string) (int64, bool) {
 	return remaining, false
 }
 
-// ClearLoginFail 登录成功后清除失败记录
-func ClearLoginFail(username string) {
+// ClearLoginFail 登录成功后清除该 IP 的失败记录
+func ClearLoginFail(username, ip string) {
 	ctx := context.Background()
-	database.RDB.Del(ctx, loginFailPrefix+username)
-	database.RDB.Del(ctx, loginLockPrefix+username)
+	database.RDB.Del(ctx, loginKey(loginFailPrefix, username, ip), loginKey(loginLockPrefix, username, ip))
 }
 
 // GlobalRateLimit 全局 API 限流（每个 IP 每分钟最多 N 次）
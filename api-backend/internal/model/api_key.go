@@ -0,0 +1,23 @@
+package model
+
+import "time"
+
+// APIKey 用户的个人 API Key，供脚本等机器间调用使用
+// 完整密钥仅在创建时返回一次，库中只保存前缀和 secret 的哈希
+type APIKey struct {
+	ID         uint       `gorm:"primarykey" json:"id"`
+	UserID     uint       `gorm:"index" json:"user_id"`
+	TenantID   uint       `gorm:"index" json:"tenant_id"`
+	Name       string     `gorm:"size:100" json:"name"`
+	Prefix     string     `gorm:"size:16;uniqueIndex" json:"prefix"`
+	SecretHash string     `gorm:"size:64" json:"-"`        // SHA-256
+	Scopes     string     `gorm:"type:text" json:"scopes"` // 逗号分隔的权限码，只能访问这些权限码对应的接口
+	ExpiresAt  *time.Time `json:"expires_at"`              // 为空表示永不过期
+	LastUsedAt *time.Time `json:"last_used_at"`
+	LastUsedIP string     `gorm:"size:45" json:"last_used_ip"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
+
+func (APIKey) TableName() string {
+	return "api_keys"
+}
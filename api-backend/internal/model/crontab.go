@@ -5,15 +5,55 @@ import "time"
 type Crontab struct {
 	TenantBaseModel
 	Name       string     `gorm:"size:200;not null" json:"name"`
-	Type       int8       `gorm:"default:0" json:"type"`
+	Type       int8       `gorm:"default:0" json:"type"` // 0=内置任务 1=HTTP回调 2=SQL维护
 	Content    string     `gorm:"type:text" json:"content"`
 	Expression string     `gorm:"size:100;not null" json:"expression"`
 	Command    string     `gorm:"size:500;not null" json:"command"`
 	Maximums   int        `gorm:"default:0" json:"maximums"`
 	Executes   int        `gorm:"default:0" json:"executes"`
+	Timeout    int        `gorm:"default:0" json:"timeout"` // 单次执行超时（秒），0=默认600秒
+	Retries    int        `gorm:"default:0" json:"retries"` // 失败重试次数，间隔按指数退避
+	Overlap    int8       `gorm:"default:0" json:"overlap"` // 上次未结束时：0=跳过 1=排队 2=允许并行
 	Status     int8       `gorm:"default:0" json:"status"`
 	Sort       int        `gorm:"default:0" json:"sort"`
+	IsSystem   int8       `gorm:"default:0" json:"is_system"` // 1=系统内置任务，不可删除
 	Remark     string     `gorm:"size:500" json:"remark"`
 	LastRunAt  *time.Time `json:"last_run_at"`
 	NextRunAt  *time.Time `json:"next_run_at"`
 }
+
+// 定时任务类型
+const (
+	CrontabTypeBuiltin = 0 // 内置任务，Command 为注册的任务名
+	CrontabTypeHTTP    = 1 // HTTP 回调，Content 为请求定义 JSON
+	CrontabTypeSQL     = 2 // SQL 维护，Command 为白名单内的语句
+)
+
+// 定时任务重叠执行策略
+const (
+	CrontabOverlapSkip  = 0 // 上次未结束则跳过本次
+	CrontabOverlapQueue = 1 // 等待上次结束后执行
+	CrontabOverlapAllow = 2 // 允许并行执行
+)
+
+// CrontabLog 定时任务执行记录
+type CrontabLog struct {
+	ID        uint      `gorm:"primarykey" json:"id"`
+	TenantID  uint      `gorm:"index" json:"tenant_id"`
+	CrontabID uint      `gorm:"index" json:"crontab_id"`
+	Name      string    `gorm:"size:200" json:"name"`
+	Node      string    `gorm:"size:100" json:"node"`      // 执行节点
+	Trigger   string    `gorm:"size:20" json:"trigger"`    // schedule=定时 manual=手动
+	Status    int8      `json:"status"`                    // 1=成功 0=失败
+	Attempts  int       `gorm:"default:1" json:"attempts"` // 执行次数（含重试）
+	Output    string    `gorm:"type:text" json:"output"`   // 执行输出（截断）
+	Error     string    `gorm:"size:1000" json:"error"`    // 错误信息（截断）
+	StartedAt time.Time `json:"started_at"`
+	EndedAt   time.Time `json:"ended_at"`
+	Duration  int64     `json:"duration"` // 毫秒
+	CreatedAt time.Time `gorm:"index" json:"created_at"`
+}
+
+func (CrontabLog) TableName() string {
+	return "crontab_logs"
+}
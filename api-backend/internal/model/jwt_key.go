@@ -0,0 +1,25 @@
+package model
+
+import "time"
+
+// JWTKey JWT 签名密钥（RS256/EdDSA），多节点共享，支持轮换
+type JWTKey struct {
+	ID         uint       `gorm:"primarykey" json:"id"`
+	Kid        string     `gorm:"size:64;uniqueIndex" json:"kid"`
+	Algorithm  string     `gorm:"size:20" json:"algorithm"`
+	PrivateKey string     `gorm:"type:text" json:"-"`          // PKCS8 PEM
+	PublicKey  string     `gorm:"type:text" json:"public_key"` // PKIX PEM
+	Status     int8       `gorm:"default:1" json:"status"`     // 1=签名中 2=仅验签（已轮换）
+	RetiredAt  *time.Time `json:"retired_at"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
+
+func (JWTKey) TableName() string {
+	return "jwt_keys"
+}
+
+// JWT 密钥状态
+const (
+	JWTKeyActive  = 1
+	JWTKeyRetired = 2
+)
@@ -0,0 +1,34 @@
+package model
+
+// LDAPConfig 租户配置的 LDAP / AD 目录，用户名密码登录时通过目录校验
+type LDAPConfig struct {
+	TenantBaseModel
+	Name               string `gorm:"size:100;not null" json:"name"`
+	URL                string `gorm:"size:255;not null" json:"url"` // ldap://host:389 或 ldaps://host:636
+	StartTLS           int8   `gorm:"default:0" json:"start_tls"`
+	InsecureSkipVerify int8   `gorm:"default:0" json:"insecure_skip_verify"` // 跳过证书校验，仅用于测试环境
+	BindDN             string `gorm:"size:255" json:"bind_dn"`               // 查询用服务账号，为空时匿名查询
+	BindPassword       string `gorm:"size:255" json:"-"`
+	BaseDN             string `gorm:"size:255;not null" json:"base_dn"`
+	UserFilter         string `gorm:"size:500" json:"user_filter"`      // %s 替换为转义后的用户名，默认 (&(objectClass=person)(uid=%s))，AD 可用 sAMAccountName
+	UsernameAttr       string `gorm:"size:50" json:"username_attr"`     // 默认 uid
+	EmailAttr          string `gorm:"size:50" json:"email_attr"`        // 默认 mail
+	NicknameAttr       string `gorm:"size:50" json:"nickname_attr"`     // 默认 displayName，为空时取 cn
+	PhoneAttr          string `gorm:"size:50" json:"phone_attr"`        // 默认 mobile
+	GroupAttr          string `gorm:"size:50" json:"group_attr"`        // 默认 memberOf
+	DisabledFilter     string `gorm:"size:500" json:"disabled_filter"`  // 匹配即视为目录中已停用，AD 可用 (userAccountControl:1.2.840.113556.1.4.803:=2)
+	GroupRoles         string `gorm:"type:text" json:"group_roles"`     // JSON：[{"group":"cn=dev,ou=groups,dc=example,dc=com","role_id":3}]
+	AutoCreate         int8   `gorm:"default:1" json:"auto_create"`     // 1=目录中存在的用户首次登录自动创建
+	DefaultRoleID      uint   `gorm:"default:0" json:"default_role_id"` // 未匹配到任何分组时分配的角色
+	Status             int8   `gorm:"default:1" json:"status"`
+}
+
+func (LDAPConfig) TableName() string {
+	return "ldap_configs"
+}
+
+// LDAPGroupRole 目录分组到本地角色的映射
+type LDAPGroupRole struct {
+	Group  string `json:"group"` // 分组 DN 或 CN，不区分大小写
+	RoleID uint   `json:"role_id"`
+}
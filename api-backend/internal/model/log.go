@@ -3,19 +3,21 @@ package model
 import "time"
 
 type OperationLog struct {
-	ID        uint      `gorm:"primarykey" json:"id"`
-	TenantID  uint      `gorm:"index" json:"tenant_id"`
-	UserID    uint      `gorm:"index" json:"user_id"`
-	Module    string    `gorm:"size:50" json:"module"`
-	Action    string    `gorm:"size:50" json:"action"`
-	Method    string    `gorm:"size:10" json:"method"`
-	Path      string    `gorm:"size:255" json:"path"`
-	Params    string    `gorm:"type:text" json:"params"`
-	Response  string    `gorm:"type:text" json:"response"`
-	IP        string    `gorm:"size:45" json:"ip"`
-	UserAgent string    `gorm:"size:500" json:"user_agent"`
-	Duration  int64     `json:"duration"`
-	CreatedAt time.Time `json:"created_at"`
+	ID       uint `gorm:"primarykey" json:"id"`
+	TenantID uint `gorm:"index" json:"tenant_id"`
+	UserID   uint `gorm:"index" json:"user_id"`
+	// ImpersonatorID 超管代登录期间的操作记录超管用户ID，UserID 为被代登录的用户
+	ImpersonatorID uint      `gorm:"index" json:"impersonator_id"`
+	Module         string    `gorm:"size:50" json:"module"`
+	Action         string    `gorm:"size:50" json:"action"`
+	Method         string    `gorm:"size:10" json:"method"`
+	Path           string    `gorm:"size:255" json:"path"`
+	Params         string    `gorm:"type:text" json:"params"`
+	Response       string    `gorm:"type:text" json:"response"`
+	IP             string    `gorm:"size:45" json:"ip"`
+	UserAgent      string    `gorm:"size:500" json:"user_agent"`
+	Duration       int64     `json:"duration"`
+	CreatedAt      time.Time `json:"created_at"`
 }
 
 func (OperationLog) TableName() string {
@@ -29,6 +31,7 @@ type LoginLog struct {
 	Username  string    `gorm:"size:50" json:"username"`
 	IP        string    `gorm:"size:45" json:"ip"`
 	UserAgent string    `gorm:"size:500" json:"user_agent"`
+	Location  string    `gorm:"size:100" json:"location"` // IP 归属地
 	Status    int8      `json:"status"`
 	Message   string    `gorm:"size:255" json:"message"`
 	CreatedAt time.Time `json:"created_at"`
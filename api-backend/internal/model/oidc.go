@@ -0,0 +1,47 @@
+package model
+
+import "time"
+
+// OIDCProvider 租户配置的 OIDC 身份提供方（企业 IdP 单点登录）
+type OIDCProvider struct {
+	TenantBaseModel
+	Name          string `gorm:"size:100;not null" json:"name"`   // 登录页按钮显示名称
+	Issuer        string `gorm:"size:255;not null" json:"issuer"` // 如 https://login.example.com/realms/corp
+	ClientID      string `gorm:"size:255;not null" json:"client_id"`
+	ClientSecret  string `gorm:"size:500" json:"-"`                     // 为空时作为公共客户端，仅使用 PKCE
+	RedirectURL   string `gorm:"size:500;not null" json:"redirect_url"` // 前端回调页地址，需在 IdP 登记
+	Scopes        string `gorm:"size:255" json:"scopes"`                // 空格分隔，默认 openid profile email
+	UsernameClaim string `gorm:"size:50" json:"username_claim"`         // 默认 preferred_username
+	EmailClaim    string `gorm:"size:50" json:"email_claim"`            // 默认 email
+	NicknameClaim string `gorm:"size:50" json:"nickname_claim"`         // 默认 name
+	PhoneClaim    string `gorm:"size:50" json:"phone_claim"`            // 默认 phone_number
+	AutoCreate    int8   `gorm:"default:1" json:"auto_create"`          // 1=首次登录自动创建用户
+	DefaultRoleID uint   `gorm:"default:0" json:"default_role_id"`      // 自动创建用户时分配的角色
+	Status        int8   `gorm:"default:1" json:"status"`
+}
+
+func (OIDCProvider) TableName() string {
+	return "oidc_providers"
+}
+
+// 外部身份类型
+const (
+	IdentityOIDC = "oidc"
+	IdentityLDAP = "ldap"
+)
+
+// UserIdentity 本地用户与外部身份的绑定关系
+// OIDC 的 Subject 为 IdP 的 sub，LDAP 的 Subject 为目录中的用户名属性值
+type UserIdentity struct {
+	ID          uint       `gorm:"primarykey" json:"id"`
+	UserID      uint       `gorm:"index" json:"user_id"`
+	Type        string     `gorm:"size:20;default:oidc;uniqueIndex:idx_type_provider_subject" json:"type"`
+	ProviderID  uint       `gorm:"uniqueIndex:idx_type_provider_subject" json:"provider_id"` // oidc_providers.id 或 ldap_configs.id
+	Subject     string     `gorm:"size:255;uniqueIndex:idx_type_provider_subject" json:"subject"`
+	LastLoginAt *time.Time `json:"last_login_at"`
+	CreatedAt   time.Time  `json:"created_at"`
+}
+
+func (UserIdentity) TableName() string {
+	return "user_identities"
+}
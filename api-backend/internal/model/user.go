@@ -26,6 +26,13 @@ type User struct {
 	LoginCount  uint       `gorm:"default:0" json:"login_count"`   // 登录次数
 	Remark      string     `gorm:"size:500" json:"remark"`         // 备注
 	Roles       []Role     `gorm:"many2many:user_roles;" json:"roles,omitempty"`
+
+	// token 版本，递增后已签发的 token 失效（仅通过 session.BumpVersion 修改）
+	TokenVersion uint `gorm:"default:0;<-:create" json:"-"`
+
+	// 密码策略
+	PasswordChangedAt  *time.Time `json:"password_changed_at"`                   // 最近修改密码时间，为空时按创建时间计算密码有效期
+	MustChangePassword int8       `gorm:"default:0" json:"must_change_password"` // 1=下次登录须先修改密码
 }
 
 func (User) TableName() string {
@@ -51,3 +58,28 @@ func (UserMenu) TableName() string {
 func (UserRole) TableName() string {
 	return "user_roles"
 }
+
+// TOTPRecoveryCode 两步验证恢复码，仅保存哈希，每个恢复码只能使用一次
+type TOTPRecoveryCode struct {
+	ID        uint       `gorm:"primarykey" json:"id"`
+	UserID    uint       `gorm:"index" json:"user_id"`
+	CodeHash  string     `gorm:"size:64;index" json:"-"` // SHA-256
+	UsedAt    *time.Time `json:"used_at"`
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+func (TOTPRecoveryCode) TableName() string {
+	return "totp_recovery_codes"
+}
+
+// PasswordHistory 密码历史，用于禁止重复使用最近的密码
+type PasswordHistory struct {
+	ID        uint      `gorm:"primarykey" json:"id"`
+	UserID    uint      `gorm:"index" json:"user_id"`
+	Hash      string    `gorm:"size:255" json:"-"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+func (PasswordHistory) TableName() string {
+	return "password_histories"
+}
@@ -0,0 +1,24 @@
+package model
+
+import "time"
+
+// WebAuthnCredential 用户注册的通行密钥（WebAuthn 凭证）
+type WebAuthnCredential struct {
+	ID              uint       `gorm:"primarykey" json:"id"`
+	UserID          uint       `gorm:"index" json:"user_id"`
+	Name            string     `gorm:"size:100" json:"name"`                      // 用户自定义名称，如「MacBook 指纹」
+	CredentialID    string     `gorm:"size:255;uniqueIndex" json:"credential_id"` // base64url
+	PublicKey       []byte     `gorm:"type:blob" json:"-"`                        // COSE 公钥
+	AttestationType string     `gorm:"size:32" json:"-"`
+	Transport       string     `gorm:"size:100" json:"transport"` // 逗号分隔，如 internal,hybrid
+	AAGUID          []byte     `gorm:"type:varbinary(16)" json:"-"`
+	SignCount       uint32     `gorm:"default:0" json:"-"`
+	BackupEligible  bool       `gorm:"default:false" json:"backup_eligible"`
+	BackupState     bool       `gorm:"default:false" json:"backup_state"` // 已在多设备间同步
+	LastUsedAt      *time.Time `json:"last_used_at"`
+	CreatedAt       time.Time  `json:"created_at"`
+}
+
+func (WebAuthnCredential) TableName() string {
+	return "webauthn_credentials"
+}
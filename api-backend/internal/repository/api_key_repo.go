@@ -0,0 +1,43 @@
+package repository
+
+import (
+	"adcms/internal/model"
+	"adcms/pkg/database"
+
+	"gorm.io/gorm"
+)
+
+type APIKeyRepository struct {
+	db *gorm.DB
+}
+
+func NewAPIKeyRepository() *APIKeyRepository {
+	return &APIKeyRepository{db: database.DB}
+}
+
+func (r *APIKeyRepository) ListByUser(userID uint) ([]model.APIKey, error) {
+	var keys []model.APIKey
+	err := r.db.Where("user_id = ?", userID).Order("id DESC").Find(&keys).Error
+	return keys, err
+}
+
+func (r *APIKeyRepository) CountByUser(userID uint) (int64, error) {
+	var count int64
+	err := r.db.Model(&model.APIKey{}).Where("user_id = ?", userID).Count(&count).Error
+	return count, err
+}
+
+func (r *APIKeyRepository) Create(key *model.APIKey) error {
+	return r.db.Create(key).Error
+}
+
+// Delete 删除用户自己的 API Key，返回是否删除成功
+func (r *APIKeyRepository) Delete(id, userID uint) (bool, error) {
+	result := r.db.Where("id = ? AND user_id = ?", id, userID).Delete(&model.APIKey{})
+	return result.RowsAffected > 0, result.Error
+}
+
+// DeleteByUser 删除用户的全部 API Key，用于密码重置、账号疑似被盗等场景
+func (r *APIKeyRepository) DeleteByUser(userID uint) error {
+	return r.db.Where("user_id = ?", userID).Delete(&model.APIKey{}).Error
+}
@@ -42,3 +42,19 @@ func (r *CrontabRepository) Update(c *model.Crontab) error {
 func (r *CrontabRepository) Delete(id uint) error {
 	return r.db.Delete(&model.Crontab{}, id).Error
 }
+
+func (r *CrontabRepository) ListLogs(crontabID uint, page, pageSize int, status string) ([]model.CrontabLog, int64, error) {
+	var logs []model.CrontabLog
+	var total int64
+
+	query := r.db.Model(&model.CrontabLog{}).Where("crontab_id = ?", crontabID)
+	if status != "" {
+		query = query.Where("status = ?", status)
+	}
+
+	query.Count(&total)
+
+	offset := (page - 1) * pageSize
+	err := query.Offset(offset).Limit(pageSize).Order("id DESC").Find(&logs).Error
+	return logs, total, err
+}
@@ -0,0 +1,91 @@
+package repository
+
+import (
+	"adcms/internal/model"
+	"adcms/pkg/database"
+
+	"gorm.io/gorm"
+)
+
+type LDAPRepository struct {
+	db *gorm.DB
+}
+
+func NewLDAPRepository() *LDAPRepository {
+	return &LDAPRepository{db: database.DB}
+}
+
+func (r *LDAPRepository) List(tenantID uint) ([]model.LDAPConfig, error) {
+	var configs []model.LDAPConfig
+	err := r.db.Where("tenant_id = ?", tenantID).Order("id DESC").Find(&configs).Error
+	return configs, err
+}
+
+// ListEnabled 租户已启用的目录配置，tenantID 为 0 时返回所有租户的
+func (r *LDAPRepository) ListEnabled(tenantID uint) ([]model.LDAPConfig, error) {
+	var configs []model.LDAPConfig
+	query := r.db.Where("status = 1")
+	if tenantID > 0 {
+		query = query.Where("tenant_id = ?", tenantID)
+	}
+	err := query.Order("id ASC").Find(&configs).Error
+	return configs, err
+}
+
+func (r *LDAPRepository) FindByID(id uint) (*model.LDAPConfig, error) {
+	var cfg model.LDAPConfig
+	err := r.db.First(&cfg, id).Error
+	return &cfg, err
+}
+
+func (r *LDAPRepository) Create(cfg *model.LDAPConfig) error {
+	return r.db.Create(cfg).Error
+}
+
+func (r *LDAPRepository) Update(cfg *model.LDAPConfig) error {
+	return r.db.Save(cfg).Error
+}
+
+// Delete 删除目录配置及其用户绑定关系
+func (r *LDAPRepository) Delete(id uint) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("type = ? AND provider_id = ?", model.IdentityLDAP, id).Delete(&model.UserIdentity{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&model.LDAPConfig{}, id).Error
+	})
+}
+
+func (r *LDAPRepository) FindIdentity(configID uint, subject string) (*model.UserIdentity, error) {
+	var ident model.UserIdentity
+	err := r.db.Where("type = ? AND provider_id = ? AND subject = ?", model.IdentityLDAP, configID, subject).First(&ident).Error
+	return &ident, err
+}
+
+// FindIdentityByUser 用户绑定的目录身份
+func (r *LDAPRepository) FindIdentityByUser(userID uint) (*model.UserIdentity, error) {
+	var ident model.UserIdentity
+	err := r.db.Where("type = ? AND user_id = ?", model.IdentityLDAP, userID).First(&ident).Error
+	return &ident, err
+}
+
+func (r *LDAPRepository) TouchIdentity(id uint) error {
+	return r.db.Model(&model.UserIdentity{}).Where("id = ?", id).Update("last_login_at", gorm.Expr("NOW()")).Error
+}
+
+// Provision 创建用户、分配角色并绑定目录身份（同一事务）
+func (r *LDAPRepository) Provision(user *model.User, roleIDs []uint, ident *model.UserIdentity) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(user).Error; err != nil {
+			return err
+		}
+		for _, roleID := range roleIDs {
+			if err := tx.Create(&model.UserRole{UserID: user.ID, RoleID: roleID}).Error; err != nil {
+				return err
+			}
+		}
+		ident.UserID = user.ID
+		ident.Type = model.IdentityLDAP
+		return tx.Create(ident).Error
+	})
+}
@@ -0,0 +1,80 @@
+package repository
+
+import (
+	"adcms/internal/model"
+	"adcms/pkg/database"
+
+	"gorm.io/gorm"
+)
+
+type OIDCRepository struct {
+	db *gorm.DB
+}
+
+func NewOIDCRepository() *OIDCRepository {
+	return &OIDCRepository{db: database.DB}
+}
+
+func (r *OIDCRepository) List(tenantID uint) ([]model.OIDCProvider, error) {
+	var providers []model.OIDCProvider
+	err := r.db.Where("tenant_id = ?", tenantID).Order("id DESC").Find(&providers).Error
+	return providers, err
+}
+
+// ListEnabled 租户已启用的身份提供方（登录页展示）
+func (r *OIDCRepository) ListEnabled(tenantID uint) ([]model.OIDCProvider, error) {
+	var providers []model.OIDCProvider
+	err := r.db.Where("tenant_id = ? AND status = 1", tenantID).Order("id ASC").Find(&providers).Error
+	return providers, err
+}
+
+func (r *OIDCRepository) FindByID(id uint) (*model.OIDCProvider, error) {
+	var p model.OIDCProvider
+	err := r.db.First(&p, id).Error
+	return &p, err
+}
+
+func (r *OIDCRepository) Create(p *model.OIDCProvider) error {
+	return r.db.Create(p).Error
+}
+
+func (r *OIDCRepository) Update(p *model.OIDCProvider) error {
+	return r.db.Save(p).Error
+}
+
+// Delete 删除身份提供方及其用户绑定关系
+func (r *OIDCRepository) Delete(id uint) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("type = ? AND provider_id = ?", model.IdentityOIDC, id).Delete(&model.UserIdentity{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&model.OIDCProvider{}, id).Error
+	})
+}
+
+func (r *OIDCRepository) FindIdentity(providerID uint, subject string) (*model.UserIdentity, error) {
+	var ident model.UserIdentity
+	err := r.db.Where("type = ? AND provider_id = ? AND subject = ?", model.IdentityOIDC, providerID, subject).First(&ident).Error
+	return &ident, err
+}
+
+func (r *OIDCRepository) TouchIdentity(id uint) error {
+	return r.db.Model(&model.UserIdentity{}).Where("id = ?", id).Update("last_login_at", gorm.Expr("NOW()")).Error
+}
+
+// Provision 创建用户、分配默认角色并绑定外部身份（同一事务）
+func (r *OIDCRepository) Provision(user *model.User, roleID uint, ident *model.UserIdentity) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(user).Error; err != nil {
+			return err
+		}
+		if roleID > 0 {
+			if err := tx.Create(&model.UserRole{UserID: user.ID, RoleID: roleID}).Error; err != nil {
+				return err
+			}
+		}
+		ident.UserID = user.ID
+		ident.Type = model.IdentityOIDC
+		return tx.Create(ident).Error
+	})
+}
@@ -3,6 +3,7 @@ package repository
 import (
 	"adcms/internal/model"
 	"adcms/pkg/database"
+	"adcms/pkg/pwpolicy"
 
 	"gorm.io/gorm"
 )
@@ -57,6 +58,13 @@ func (r *UserRepository) FindByEmailGlobal(email string) (*model.User, error) {
 	return &user, err
 }
 
+// ListByPhone 查找绑定该手机号的用户（手机号不保证全局唯一）
+func (r *UserRepository) ListByPhone(phone string) ([]model.User, error) {
+	var users []model.User
+	err := r.db.Where("phone = ? AND phone != ''", phone).Limit(10).Find(&users).Error
+	return users, err
+}
+
 func (r *UserRepository) List(tenantID uint, page, pageSize int, keyword string) ([]model.User, int64, error) {
 	var users []model.User
 	var total int64
@@ -77,8 +85,54 @@ func (r *UserRepository) List(tenantID uint, page, pageSize int, keyword string)
 	return users, total, err
 }
 
+// UpdatePassword 用户本人修改密码：记录修改时间和密码历史，并清除强制修改标记
 func (r *UserRepository) UpdatePassword(id uint, password string) error {
-	return r.db.Model(&model.User{}).Where("id = ?", id).Update("password", password).Error
+	return r.setPassword(id, password, 0)
+}
+
+// ResetPassword 管理员重置密码，用户下次登录须先修改
+func (r *UserRepository) ResetPassword(id uint, password string) error {
+	return r.setPassword(id, password, 1)
+}
+
+func (r *UserRepository) setPassword(id uint, password string, mustChange int8) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&model.User{}).Where("id = ?", id).Updates(map[string]interface{}{
+			"password":             password,
+			"password_changed_at":  gorm.Expr("NOW()"),
+			"must_change_password": mustChange,
+		}).Error; err != nil {
+			return err
+		}
+		if err := tx.Create(&model.PasswordHistory{UserID: id, Hash: password}).Error; err != nil {
+			return err
+		}
+		// 只保留策略可能用到的最近记录
+		var keep []uint
+		tx.Model(&model.PasswordHistory{}).Where("user_id = ?", id).
+			Order("id DESC").Limit(pwpolicy.MaxHistory).Pluck("id", &keep)
+		return tx.Where("user_id = ? AND id NOT IN ?", id, keep).Delete(&model.PasswordHistory{}).Error
+	})
+}
+
+// InvalidatePassword 作废用户当前密码（写入不可能匹配的哈希），只能通过邮件或短信找回密码重新设置
+func (r *UserRepository) InvalidatePassword(id uint) error {
+	return r.db.Model(&model.User{}).Where("id = ?", id).Update("password", "!").Error
+}
+
+// RecentPasswordHashes 最近 n 次使用过的密码哈希（按时间倒序）
+func (r *UserRepository) RecentPasswordHashes(userID uint, n int) ([]string, error) {
+	var hashes []string
+	err := r.db.Model(&model.PasswordHistory{}).Where("user_id = ?", userID).
+		Order("id DESC").Limit(n).Pluck("hash", &hashes).Error
+	return hashes, err
+}
+
+// HasDirectoryIdentity 用户是否绑定了目录（LDAP）身份，此类用户的密码由目录管理
+func (r *UserRepository) HasDirectoryIdentity(userID uint) bool {
+	var count int64
+	r.db.Model(&model.UserIdentity{}).Where("user_id = ? AND type = ?", userID, model.IdentityLDAP).Count(&count)
+	return count > 0
 }
 
 func (r *UserRepository) UpdateTOTP(id uint, enabled int8, secret string) error {
@@ -88,6 +142,43 @@ func (r *UserRepository) UpdateTOTP(id uint, enabled int8, secret string) error
 	}).Error
 }
 
+// ReplaceRecoveryCodes 用新的恢复码哈希替换用户全部恢复码
+func (r *UserRepository) ReplaceRecoveryCodes(userID uint, hashes []string) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("user_id = ?", userID).Delete(&model.TOTPRecoveryCode{}).Error; err != nil {
+			return err
+		}
+		codes := make([]model.TOTPRecoveryCode, len(hashes))
+		for i, hash := range hashes {
+			codes[i] = model.TOTPRecoveryCode{UserID: userID, CodeHash: hash}
+		}
+		if len(codes) == 0 {
+			return nil
+		}
+		return tx.Create(&codes).Error
+	})
+}
+
+// UseRecoveryCode 核销一个未使用的恢复码，返回是否核销成功
+func (r *UserRepository) UseRecoveryCode(userID uint, hash string) (bool, error) {
+	result := r.db.Model(&model.TOTPRecoveryCode{}).
+		Where("user_id = ? AND code_hash = ? AND used_at IS NULL", userID, hash).
+		Update("used_at", gorm.Expr("NOW()"))
+	return result.RowsAffected > 0, result.Error
+}
+
+// CountRecoveryCodes 统计用户剩余可用的恢复码数量
+func (r *UserRepository) CountRecoveryCodes(userID uint) (int64, error) {
+	var count int64
+	err := r.db.Model(&model.TOTPRecoveryCode{}).Where("user_id = ? AND used_at IS NULL", userID).Count(&count).Error
+	return count, err
+}
+
+// DeleteRecoveryCodes 删除用户全部恢复码
+func (r *UserRepository) DeleteRecoveryCodes(userID uint) error {
+	return r.db.Where("user_id = ?", userID).Delete(&model.TOTPRecoveryCode{}).Error
+}
+
 func (r *UserRepository) UpdateStatus(id uint, status int8) error {
 	return r.db.Model(&model.User{}).Where("id = ?", id).Update("status", status).Error
 }
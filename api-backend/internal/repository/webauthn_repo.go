@@ -0,0 +1,47 @@
+package repository
+
+import (
+	"adcms/internal/model"
+	"adcms/pkg/database"
+
+	"gorm.io/gorm"
+)
+
+type WebAuthnRepository struct {
+	db *gorm.DB
+}
+
+func NewWebAuthnRepository() *WebAuthnRepository {
+	return &WebAuthnRepository{db: database.DB}
+}
+
+func (r *WebAuthnRepository) ListByUser(userID uint) ([]model.WebAuthnCredential, error) {
+	var creds []model.WebAuthnCredential
+	err := r.db.Where("user_id = ?", userID).Order("id DESC").Find(&creds).Error
+	return creds, err
+}
+
+func (r *WebAuthnRepository) CountByUser(userID uint) (int64, error) {
+	var count int64
+	err := r.db.Model(&model.WebAuthnCredential{}).Where("user_id = ?", userID).Count(&count).Error
+	return count, err
+}
+
+func (r *WebAuthnRepository) Create(cred *model.WebAuthnCredential) error {
+	return r.db.Create(cred).Error
+}
+
+// UpdateUsage 登录成功后更新签名计数、同步状态和最后使用时间
+func (r *WebAuthnRepository) UpdateUsage(cred *model.WebAuthnCredential) error {
+	return r.db.Model(&model.WebAuthnCredential{}).Where("id = ?", cred.ID).Updates(map[string]interface{}{
+		"sign_count":   cred.SignCount,
+		"backup_state": cred.BackupState,
+		"last_used_at": cred.LastUsedAt,
+	}).Error
+}
+
+// Delete 删除用户自己的凭证，返回是否删除成功
+func (r *WebAuthnRepository) Delete(id, userID uint) (bool, error) {
+	result := r.db.Where("id = ? AND user_id = ?", id, userID).Delete(&model.WebAuthnCredential{})
+	return result.RowsAffected > 0, result.Error
+}
@@ -21,6 +21,9 @@ func SetupRouter(mode string) *gin.Engine {
 	r.Static("/uploads", "./uploads")
 	r.GET("/swagger/*any", ginSwagger.WrapHandler(swaggerFiles.Handler))
 
+	jwtKeyHandler := handler.NewJWTKeyHandler()
+	r.GET("/.well-known/jwks.json", jwtKeyHandler.JWKS)
+
 	authHandler := handler.NewAuthHandler()
 	menuHandler := handler.NewMenuHandler()
 	userHandler := handler.NewUserHandler()
@@ -41,16 +44,32 @@ func SetupRouter(mode string) *gin.Engine {
 	crontabHandler := handler.NewCrontabHandler()
 	databaseHandler := handler.NewDatabaseHandler()
 	cityHandler := handler.NewCityHandler()
+	oidcHandler := handler.NewOIDCHandler()
+	ldapHandler := handler.NewLDAPHandler()
 
 	api := r.Group("/api")
 	api.Use(middleware.GlobalRateLimit(300)) // 每个IP每分钟最多300次请求
 	{
 		auth := api.Group("/auth")
 		{
+			auth.GET("/captcha", middleware.RateLimit(30, time.Minute), authHandler.Captcha)
 			auth.POST("/login", middleware.RateLimit(10, time.Minute), authHandler.Login)
+			auth.POST("/login-sms/code", middleware.RateLimit(5, time.Minute), authHandler.SendLoginSmsCode)
+			auth.POST("/login-sms", middleware.RateLimit(10, time.Minute), authHandler.LoginBySms)
 			auth.POST("/verify-totp", middleware.RateLimit(10, time.Minute), authHandler.VerifyTOTP)
+			auth.POST("/webauthn/login/begin", middleware.RateLimit(10, time.Minute), authHandler.WebAuthnLoginBegin)
+			auth.POST("/webauthn/login/finish", middleware.RateLimit(10, time.Minute), authHandler.WebAuthnLoginFinish)
+			auth.GET("/oidc/providers", authHandler.OIDCProviders)
+			auth.GET("/oidc/:id/authorize", middleware.RateLimit(10, time.Minute), authHandler.OIDCAuthorize)
+			auth.POST("/oidc/callback", middleware.RateLimit(10, time.Minute), authHandler.OIDCCallback)
+			auth.POST("/refresh", middleware.RateLimit(30, time.Minute), authHandler.Refresh)
 			auth.POST("/forgot-password", middleware.RateLimit(5, time.Minute), authHandler.ForgotPassword)
 			auth.POST("/reset-password", middleware.RateLimit(10, time.Minute), authHandler.ResetPasswordByEmail)
+			auth.POST("/forgot-password-sms", middleware.RateLimit(5, time.Minute), authHandler.ForgotPasswordBySms)
+			auth.POST("/reset-password-sms", middleware.RateLimit(10, time.Minute), authHandler.ResetPasswordBySms)
+			auth.POST("/expired-password", middleware.RateLimit(10, time.Minute), authHandler.ChangeExpiredPassword)
+			auth.GET("/login-alert/:token", middleware.RateLimit(10, time.Minute), authHandler.LoginAlertConfirm)
+			auth.POST("/login-alert/:token", middleware.RateLimit(10, time.Minute), authHandler.LoginAlertRevoke)
 		}
 
 		protected := api.Group("")
@@ -59,20 +78,38 @@ func SetupRouter(mode string) *gin.Engine {
 		protected.Use(middleware.DataScopeFilter())
 		protected.Use(middleware.OperationLogger())
 		{
+			// 敏感操作要求 5 分钟内验证过密码或两步验证码
+			recentAuth := middleware.RequireRecentAuth(5 * time.Minute)
+			// 超管代登录期间不允许修改被代登录用户的账号安全设置及邮箱、手机等找回凭据
+			noImpersonation := middleware.DenyImpersonation()
+
 			// Auth
 			protectedAuth := protected.Group("/auth")
 			{
 				protectedAuth.GET("/user-info", authHandler.GetUserInfo)
-				protectedAuth.PUT("/user-info", authHandler.UpdateUserInfo)
-				protectedAuth.PUT("/password", authHandler.ChangePassword)
+				protectedAuth.PUT("/user-info", noImpersonation, authHandler.UpdateUserInfo)
+				protectedAuth.PUT("/password", noImpersonation, authHandler.ChangePassword)
 				protectedAuth.POST("/logout", authHandler.Logout)
-				protectedAuth.POST("/totp/generate", authHandler.GenerateTOTP)
-				protectedAuth.POST("/totp/bind", authHandler.BindTOTP)
-				protectedAuth.POST("/totp/disable", authHandler.DisableTOTP)
+				protectedAuth.POST("/confirm", noImpersonation, middleware.RateLimit(10, time.Minute), authHandler.Confirm)
+				protectedAuth.POST("/impersonation/exit", authHandler.ExitImpersonation)
+				protectedAuth.POST("/totp/generate", noImpersonation, authHandler.GenerateTOTP)
+				protectedAuth.POST("/totp/bind", noImpersonation, authHandler.BindTOTP)
+				protectedAuth.POST("/totp/disable", noImpersonation, recentAuth, authHandler.DisableTOTP)
+				protectedAuth.GET("/totp/recovery-codes", noImpersonation, authHandler.RecoveryCodes)
+				protectedAuth.POST("/totp/recovery-codes", noImpersonation, authHandler.RegenerateRecoveryCodes)
+				protectedAuth.GET("/webauthn/credentials", authHandler.WebAuthnCredentials)
+				protectedAuth.DELETE("/webauthn/credentials/:id", noImpersonation, authHandler.DeleteWebAuthnCredential)
+				protectedAuth.POST("/webauthn/register/begin", noImpersonation, authHandler.WebAuthnRegisterBegin)
+				protectedAuth.POST("/webauthn/register/finish", noImpersonation, authHandler.WebAuthnRegisterFinish)
 				protectedAuth.GET("/codes", authHandler.GetPermissionCodes)
 				protectedAuth.GET("/login-history", authHandler.LoginHistory)
-				protectedAuth.POST("/send-sms-code", authHandler.SendSmsCode)
-				protectedAuth.POST("/bind-phone", authHandler.BindPhone)
+				protectedAuth.GET("/sessions", authHandler.Sessions)
+				protectedAuth.DELETE("/sessions/:id", authHandler.RevokeSession)
+				protectedAuth.GET("/api-keys", authHandler.APIKeys)
+				protectedAuth.POST("/api-keys", noImpersonation, recentAuth, authHandler.CreateAPIKey)
+				protectedAuth.DELETE("/api-keys/:id", authHandler.DeleteAPIKey)
+				protectedAuth.POST("/send-sms-code", noImpersonation, authHandler.SendSmsCode)
+				protectedAuth.POST("/bind-phone", noImpersonation, authHandler.BindPhone)
 			}
 
 			// Menus
@@ -100,11 +137,14 @@ func SetupRouter(mode string) *gin.Engine {
 				users.PUT("/:id", userHandler.Update)
 				users.DELETE("/:id", userHandler.Delete)
 				users.PUT("/:id/status", userHandler.ToggleStatus)
-				users.PUT("/:id/reset-password", userHandler.ResetPassword)
+				users.PUT("/:id/reset-password", recentAuth, userHandler.ResetPassword)
 				users.PUT("/:id/roles", userHandler.AssignRoles)
 				users.PUT("/:id/menus", userHandler.AssignMenus) // 新增
 				users.PUT("/:id/unlock", userHandler.UnlockUser)
-				users.POST("/:id/login-as", userHandler.LoginAs)
+				users.POST("/:id/login-as", recentAuth, userHandler.LoginAs)
+				users.POST("/:id/reset-totp", recentAuth, userHandler.ResetTOTP)
+				users.GET("/:id/sessions", userHandler.Sessions)
+				users.DELETE("/:id/sessions", userHandler.RevokeSessions)
 				users.GET("/export", userHandler.Export)
 				users.GET("/import-template", userHandler.ImportTemplate)
 				users.POST("/import", userHandler.Import)
@@ -117,9 +157,9 @@ func SetupRouter(mode string) *gin.Engine {
 				admins.GET("/:id", adminHandler.Detail)
 				admins.POST("", adminHandler.Create)
 				admins.PUT("/:id", adminHandler.Update)
-				admins.DELETE("/:id", adminHandler.Delete)
+				admins.DELETE("/:id", recentAuth, adminHandler.Delete)
 				admins.PUT("/:id/status", adminHandler.ToggleStatus)
-				admins.PUT("/:id/reset-password", adminHandler.ResetPassword)
+				admins.PUT("/:id/reset-password", recentAuth, adminHandler.ResetPassword)
 				admins.GET("/:id/statistics", adminHandler.Statistics)
 			}
 
@@ -182,6 +222,7 @@ func SetupRouter(mode string) *gin.Engine {
 			{
 				media.GET("", mediaHandler.List)
 				media.POST("/upload", mediaHandler.Upload)
+				media.POST("/upload/temp", mediaHandler.UploadTemp)
 				media.DELETE("/:id", mediaHandler.Delete)
 			}
 
@@ -192,13 +233,36 @@ func SetupRouter(mode string) *gin.Engine {
 				configs.PUT("", configHandler.Update)
 				configs.GET("/by-group", configHandler.ListByGroup)
 				configs.GET("/email", configHandler.GetEmailConfig)
-				configs.PUT("/email", configHandler.UpdateEmailConfig)
+				configs.PUT("/email", recentAuth, configHandler.UpdateEmailConfig)
 				configs.POST("/email/test", configHandler.TestEmail)
 				configs.GET("/sms", configHandler.GetSmsConfig)
-				configs.PUT("/sms", configHandler.UpdateSmsConfig)
+				configs.PUT("/sms", recentAuth, configHandler.UpdateSmsConfig)
 				configs.POST("/sms/test", configHandler.TestSms)
 				configs.GET("/log", configHandler.GetLogConfig)
 				configs.PUT("/log", configHandler.UpdateLogConfig)
+				configs.GET("/password-policy", configHandler.GetPasswordPolicy)
+				configs.PUT("/password-policy", configHandler.UpdatePasswordPolicy)
+				configs.GET("/jwt-keys", jwtKeyHandler.List)
+				configs.POST("/jwt-keys/rotate", jwtKeyHandler.Rotate)
+			}
+
+			// OIDC Providers - 租户单点登录配置
+			oidcProviders := protected.Group("/oidc-providers")
+			{
+				oidcProviders.GET("", oidcHandler.List)
+				oidcProviders.POST("", oidcHandler.Create)
+				oidcProviders.PUT("/:id", oidcHandler.Update)
+				oidcProviders.DELETE("/:id", oidcHandler.Delete)
+			}
+
+			// LDAP Configs - 租户 LDAP / AD 目录登录配置
+			ldapConfigs := protected.Group("/ldap-configs")
+			{
+				ldapConfigs.GET("", ldapHandler.List)
+				ldapConfigs.POST("", ldapHandler.Create)
+				ldapConfigs.PUT("/:id", ldapHandler.Update)
+				ldapConfigs.DELETE("/:id", ldapHandler.Delete)
+				ldapConfigs.POST("/:id/test", ldapHandler.Test)
 			}
 
 			// Config Groups
@@ -278,9 +342,15 @@ func SetupRouter(mode string) *gin.Engine {
 			crontabs := protected.Group("/crontabs")
 			{
 				crontabs.GET("", crontabHandler.List)
+				crontabs.GET("/builtins", crontabHandler.Builtins)
 				crontabs.POST("", crontabHandler.Create)
+				crontabs.POST("/preview", crontabHandler.Preview)
 				crontabs.PUT("/:id", crontabHandler.Update)
 				crontabs.DELETE("/:id", crontabHandler.Delete)
+				crontabs.GET("/:id/logs", crontabHandler.Logs)
+				crontabs.POST("/:id/run", crontabHandler.Run)
+				crontabs.PUT("/:id/pause", crontabHandler.Pause)
+				crontabs.PUT("/:id/resume", crontabHandler.Resume)
 			}
 
 			// Database
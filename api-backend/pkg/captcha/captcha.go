@@ -0,0 +1,192 @@
+package captcha
+
+import (
+	"adcms/pkg/database"
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"image"
+	"image/color"
+	"image/png"
+	"math/big"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// 图形验证码：服务端生成数字图片，答案存 Redis，校验一次即作废，不依赖外部服务
+const (
+	Width  = 160
+	Height = 60
+	Length = 5
+
+	answerTTL = 5 * time.Minute
+	keyPrefix = "captcha:ans:"
+)
+
+// idPattern 验证码ID格式（16 字节随机数的十六进制），拒绝拼接其他 Redis 键的伪造ID
+var idPattern = regexp.MustCompile(`^[0-9a-f]{32}$`)
+
+// Challenge 下发给前端的验证码
+type Challenge struct {
+	ID    string `json:"captcha_id"`
+	Image string `json:"image"` // data:image/png;base64,...
+}
+
+// New 生成验证码并保存答案
+func New() (*Challenge, error) {
+	answer, err := randomDigits(Length)
+	if err != nil {
+		return nil, err
+	}
+	img, err := Render(answer)
+	if err != nil {
+		return nil, err
+	}
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return nil, err
+	}
+	id := hex.EncodeToString(buf)
+	if err := database.RDB.Set(context.Background(), keyPrefix+id, answer, answerTTL).Err(); err != nil {
+		return nil, err
+	}
+	return &Challenge{ID: id, Image: "data:image/png;base64," + base64.StdEncoding.EncodeToString(img)}, nil
+}
+
+// Verify 校验答案，无论对错验证码都会作废，防止对同一张图反复尝试
+func Verify(id, answer string) bool {
+	if !idPattern.MatchString(id) || answer == "" {
+		return false
+	}
+	ctx := context.Background()
+	key := keyPrefix + id
+	stored, err := database.RDB.Get(ctx, key).Result()
+	if err != nil {
+		return false
+	}
+	database.RDB.Del(ctx, key)
+	return stored == strings.TrimSpace(answer)
+}
+
+// digits 5x7 点阵字形，省略易与字母混淆的 0 和 1
+var digits = map[byte][7]string{
+	'2': {"01110", "10001", "00001", "00010", "00100", "01000", "11111"},
+	'3': {"11110", "00001", "00001", "01110", "00001", "00001", "11110"},
+	'4': {"00010", "00110", "01010", "10010", "11111", "00010", "00010"},
+	'5': {"11111", "10000", "11110", "00001", "00001", "10001", "01110"},
+	'6': {"00110", "01000", "10000", "11110", "10001", "10001", "01110"},
+	'7': {"11111", "00001", "00010", "00100", "01000", "01000", "01000"},
+	'8': {"01110", "10001", "10001", "01110", "10001", "10001", "01110"},
+	'9': {"01110", "10001", "10001", "01111", "00001", "00010", "01100"},
+}
+
+const digitChars = "23456789"
+
+func randomDigits(n int) (string, error) {
+	buf := make([]byte, n)
+	for i := range buf {
+		k, err := randInt(len(digitChars))
+		if err != nil {
+			return "", err
+		}
+		buf[i] = digitChars[k]
+	}
+	return string(buf), nil
+}
+
+func randInt(n int) (int, error) {
+	v, err := rand.Int(rand.Reader, big.NewInt(int64(n)))
+	if err != nil {
+		return 0, err
+	}
+	return int(v.Int64()), nil
+}
+
+// mustRandInt 仅用于绘制干扰，随机源出错时退化为 0
+func mustRandInt(n int) int {
+	v, _ := randInt(n)
+	return v
+}
+
+// Render 将答案绘制为带倾斜、位移和干扰线的 PNG 图片
+func Render(answer string) ([]byte, error) {
+	img := image.NewRGBA(image.Rect(0, 0, Width, Height))
+	bg := color.RGBA{uint8(235 + mustRandInt(20)), uint8(235 + mustRandInt(20)), uint8(235 + mustRandInt(20)), 255}
+	for y := 0; y < Height; y++ {
+		for x := 0; x < Width; x++ {
+			img.Set(x, y, bg)
+		}
+	}
+
+	// 背景干扰点
+	for i := 0; i < Width*Height/20; i++ {
+		img.Set(mustRandInt(Width), mustRandInt(Height), randomColor(120, 200))
+	}
+
+	const scale = 4
+	cell := Width / (len(answer) + 1)
+	for i := 0; i < len(answer); i++ {
+		glyph, ok := digits[answer[i]]
+		if !ok {
+			continue
+		}
+		c := randomColor(20, 120)
+		x0 := cell/2 + i*cell + mustRandInt(7) - 3
+		y0 := (Height-7*scale)/2 + mustRandInt(11) - 5
+		shear := float64(mustRandInt(7)-3) / 10 // 每个字符随机倾斜
+		for row, bits := range glyph {
+			dx := int(shear * float64(3-row) * scale)
+			for col := range bits {
+				if bits[col] != '1' {
+					continue
+				}
+				for py := 0; py < scale; py++ {
+					for px := 0; px < scale; px++ {
+						img.Set(x0+dx+col*scale+px, y0+row*scale+py, c)
+					}
+				}
+			}
+		}
+	}
+
+	// 穿过字符的干扰线
+	for i := 0; i < 4; i++ {
+		drawLine(img, mustRandInt(Width/4), mustRandInt(Height), Width-mustRandInt(Width/4), mustRandInt(Height), randomColor(40, 160))
+	}
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func randomColor(min, max int) color.RGBA {
+	n := max - min
+	return color.RGBA{uint8(min + mustRandInt(n)), uint8(min + mustRandInt(n)), uint8(min + mustRandInt(n)), 255}
+}
+
+func drawLine(img *image.RGBA, x0, y0, x1, y1 int, c color.RGBA) {
+	steps := x1 - x0
+	if steps < 0 {
+		steps = -steps
+	}
+	if dy := y1 - y0; dy > steps || -dy > steps {
+		steps = dy
+		if steps < 0 {
+			steps = -steps
+		}
+	}
+	if steps == 0 {
+		return
+	}
+	for i := 0; i <= steps; i++ {
+		x := x0 + (x1-x0)*i/steps
+		y := y0 + (y1-y0)*i/steps
+		img.Set(x, y, c)
+		img.Set(x, y+1, c)
+	}
+}
@@ -0,0 +1,45 @@
+package captcha
+
+import (
+	"bytes"
+	"image/png"
+	"strings"
+	"testing"
+)
+
+func TestRender(t *testing.T) {
+	answer, err := randomDigits(Length)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(answer) != Length || strings.Trim(answer, digitChars) != "" {
+		t.Fatalf("unexpected answer %q", answer)
+	}
+
+	data, err := Render(answer)
+	if err != nil {
+		t.Fatalf("Render failed: %v", err)
+	}
+	img, err := png.Decode(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("invalid png: %v", err)
+	}
+	if b := img.Bounds(); b.Dx() != Width || b.Dy() != Height {
+		t.Fatalf("unexpected size %v", b)
+	}
+
+	// 相同答案每次绘制结果不同
+	again, _ := Render(answer)
+	if bytes.Equal(data, again) {
+		t.Fatal("render should be randomized")
+	}
+}
+
+func TestVerifyRejectsForeignID(t *testing.T) {
+	// 格式不符的ID直接拒绝，不会访问 Redis
+	for _, id := range []string{"", "risk:login:sub:admin", "ABCDEF0123456789ABCDEF0123456789", "0123456789abcdef"} {
+		if Verify(id, "1") {
+			t.Errorf("Verify(%q) should be false", id)
+		}
+	}
+}
@@ -0,0 +1,67 @@
+package captcha
+
+import (
+	"adcms/pkg/database"
+	"context"
+	"fmt"
+	"time"
+)
+
+// Scene 需要图形验证码的场景：同一 IP 或同一对象（用户名、邮箱、用户）在窗口期内的
+// 风险次数达到阈值后，后续请求须先通过图形验证码。比账号锁定更早介入，
+// 避免攻击者仅凭用户名就能把他人账号锁定。
+type Scene struct {
+	Name             string
+	IPThreshold      int64
+	SubjectThreshold int64
+	Window           time.Duration
+}
+
+var (
+	// Login 登录失败
+	Login = Scene{Name: "login", IPThreshold: 10, SubjectThreshold: 2, Window: 15 * time.Minute}
+	// ForgotPassword 找回密码发送邮件
+	ForgotPassword = Scene{Name: "forgot", IPThreshold: 3, SubjectThreshold: 2, Window: 30 * time.Minute}
+	// SmsCode 发送短信验证码
+	SmsCode = Scene{Name: "sms", IPThreshold: 5, SubjectThreshold: 3, Window: time.Hour}
+)
+
+func (s Scene) ipKey(ip string) string {
+	return fmt.Sprintf("captcha:risk:%s:ip:%s", s.Name, ip)
+}
+
+func (s Scene) subjectKey(subject string) string {
+	return fmt.Sprintf("captcha:risk:%s:sub:%s", s.Name, subject)
+}
+
+// Required 是否需要图形验证码
+func (s Scene) Required(ip, subject string) bool {
+	ctx := context.Background()
+	if n, _ := database.RDB.Get(ctx, s.ipKey(ip)).Int64(); n >= s.IPThreshold {
+		return true
+	}
+	if subject == "" {
+		return false
+	}
+	n, _ := database.RDB.Get(ctx, s.subjectKey(subject)).Int64()
+	return n >= s.SubjectThreshold
+}
+
+// Record 记录一次风险行为（登录失败、发送验证码等）
+func (s Scene) Record(ip, subject string) {
+	ctx := context.Background()
+	keys := []string{s.ipKey(ip)}
+	if subject != "" {
+		keys = append(keys, s.subjectKey(subject))
+	}
+	for _, key := range keys {
+		if n, _ := database.RDB.Incr(ctx, key).Result(); n == 1 {
+			database.RDB.Expire(ctx, key, s.Window)
+		}
+	}
+}
+
+// Clear 清除对象的风险记录（如登录成功），IP 计数保留至窗口期结束
+func (s Scene) Clear(subject string) {
+	database.RDB.Del(context.Background(), s.subjectKey(subject))
+}
@@ -0,0 +1,106 @@
+package crontab
+
+import (
+	"adcms/internal/model"
+	"adcms/pkg/database"
+	"context"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// 多实例部署时，每个进程都会启动调度器：
+//   - 定时触发按「任务ID + 调度时刻」在 Redis 抢占租约，同一时刻只有一个节点执行
+//   - 任务新增/修改/暂停/删除通过 Redis 发布订阅通知其他节点重新加载
+const (
+	lockKeyPrefix = "cron:lock:"
+	syncChannel   = "cron:sync"
+)
+
+var syncCancel context.CancelFunc
+
+// acquireTick 抢占任务在某个调度时刻的执行权
+// 租约时长与任务超时（含重试）一致，到期前不主动释放，避免时钟略有偏差的节点在同一时刻重复执行
+func acquireTick(id uint, tick time.Time, ttl time.Duration) (bool, error) {
+	if database.RDB == nil {
+		return true, nil
+	}
+	key := fmt.Sprintf("%s%d:%d", lockKeyPrefix, id, tick.Unix())
+	return database.RDB.SetNX(context.Background(), key, NodeID, ttl).Result()
+}
+
+// claimTick 抢占本次调度时刻，失败或被其他节点抢到时返回 false
+func claimTick(id uint, ttl time.Duration) bool {
+	ok, err := acquireTick(id, time.Now().Truncate(time.Second), ttl)
+	if err != nil {
+		log.Printf("[Cron] 任务[%d] 获取执行锁失败，跳过本次执行: %v", id, err)
+		return false
+	}
+	return ok
+}
+
+// publish 通知其他节点任务已变更
+func publish(id uint) {
+	if database.RDB == nil || id == 0 {
+		return
+	}
+	msg := fmt.Sprintf("%s|%d", NodeID, id)
+	if err := database.RDB.Publish(context.Background(), syncChannel, msg).Err(); err != nil {
+		log.Printf("[Cron] 发布任务[%d]变更通知失败: %v", id, err)
+	}
+}
+
+// startSync 订阅任务变更通知，收到后按数据库最新配置重新注册
+func startSync() {
+	if database.RDB == nil {
+		return
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	syncCancel = cancel
+	pubsub := database.RDB.Subscribe(ctx, syncChannel)
+
+	go func() {
+		defer pubsub.Close()
+		ch := pubsub.Channel()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case msg, ok := <-ch:
+				if !ok {
+					return
+				}
+				node, idStr, found := strings.Cut(msg.Payload, "|")
+				if !found || node == NodeID {
+					continue
+				}
+				id, err := strconv.ParseUint(idStr, 10, 64)
+				if err != nil {
+					continue
+				}
+				syncJob(uint(id))
+			}
+		}
+	}()
+}
+
+func stopSync() {
+	if syncCancel != nil {
+		syncCancel()
+		syncCancel = nil
+	}
+}
+
+// syncJob 按数据库中的最新状态在本节点注册或移除任务
+func syncJob(id uint) {
+	var job model.Crontab
+	if err := database.DB.First(&job, id).Error; err != nil {
+		unschedule(id)
+		return
+	}
+	if err := schedule(&job); err != nil {
+		log.Printf("[Cron] 同步任务[%d]%s失败: %v", job.ID, job.Name, err)
+	}
+}
@@ -3,32 +3,58 @@ package crontab
 import (
 	"adcms/internal/model"
 	"adcms/pkg/database"
+	"adcms/pkg/storage"
 	"context"
 	"fmt"
 	"log"
+	"sync"
 	"time"
 
+	"github.com/go-redis/redis/v8"
 	"github.com/robfig/cron/v3"
+	"gorm.io/gorm"
 )
 
 var C *cron.Cron
 
+var (
+	mu      sync.Mutex
+	entries = make(map[uint]cron.EntryID) // crontab ID -> cron entry
+)
+
+func init() {
+	Register("CleanExpiredTokens", "清理过期token缓存", CleanExpiredTokens)
+	Register("CleanTempFiles", "清理过期的临时上传文件", CleanTempFiles)
+	Register("CleanExpiredLocks", "清理过期登录锁定/限流记录", CleanExpiredLocks)
+	Register("CleanOldOperationLogs", "按保留天数清理操作/登录/邮件/短信日志", CleanOldOperationLogs)
+	Register("CleanCrontabLogs", "清理过期的定时任务执行记录", CleanCrontabLogs)
+	Register("SyncLDAPAccounts", "同步目录中已停用的账号", SyncLDAPAccounts)
+}
+
+// systemJobs 系统内置定时任务，启动时同步到 crontabs 表，可在后台暂停/恢复或修改执行时间
+var systemJobs = []struct {
+	Command    string
+	Expression string
+}{
+	{"CleanOldOperationLogs", "0 0 1 * * *"}, // 每天凌晨1点按保留天数清理日志
+	{"CleanExpiredTokens", "0 0 2 * * *"},    // 每天凌晨2点清理过期token
+	{"CleanTempFiles", "0 0 3 * * *"},        // 每天凌晨3点清理临时文件
+	{"CleanCrontabLogs", "0 0 4 * * *"},      // 每天凌晨4点清理过期的任务执行记录
+	{"CleanExpiredLocks", "0 0 * * * *"},     // 每小时清理过期的登录锁定记录
+	{"SyncLDAPAccounts", "0 */30 * * * *"},   // 每30分钟同步目录中已停用的账号
+}
+
 // Setup 初始化定时任务调度器
 func Setup() {
 	C = cron.New(cron.WithSeconds())
 
-	// 每天凌晨2点清理过期token
-	C.AddFunc("0 0 2 * * *", CleanExpiredTokens)
+	syncSystemJobs()
 
-	// 每天凌晨3点清理临时文件
-	C.AddFunc("0 0 3 * * *", CleanTempFiles)
-
-	// 每小时清理过期的登录锁定记录
-	C.AddFunc("0 0 * * * *", CleanExpiredLocks)
-
-	// 每天凌晨1点清理30天前的操作日志
-	C.AddFunc("0 0 1 * * *", CleanOldOperationLogs)
+	// 加载数据库中启用的定时任务（含系统任务）
+	LoadJobs()
 
+	// 订阅其他节点的任务变更通知
+	startSync()
 
 	C.Start()
 	log.Println("[Cron] 定时任务调度器已启动")
@@ -36,84 +62,340 @@ func Setup() {
 
 // Stop 停止调度器
 func Stop() {
+	stopSync()
 	if C != nil {
 		C.Stop()
 		log.Println("[Cron] 定时任务调度器已停止")
 	}
 }
 
+// syncSystemJobs 将缺失的系统任务写入 crontabs 表，已存在的保留后台修改过的配置
+func syncSystemJobs() {
+	for i, sj := range systemJobs {
+		var count int64
+		database.DB.Model(&model.Crontab{}).Where("is_system = 1 AND command = ?", sj.Command).Count(&count)
+		if count > 0 {
+			continue
+		}
+		title := sj.Command
+		for _, b := range Builtins() {
+			if b.Name == sj.Command {
+				title = b.Title
+			}
+		}
+		job := model.Crontab{
+			Name:       title,
+			Type:       model.CrontabTypeBuiltin,
+			Expression: sj.Expression,
+			Command:    sj.Command,
+			Status:     1,
+			Sort:       i,
+			IsSystem:   1,
+			Remark:     "系统内置任务",
+		}
+		if err := database.DB.Create(&job).Error; err != nil {
+			log.Printf("[Cron] 同步系统任务%s失败: %v", sj.Command, err)
+		}
+	}
+}
+
+// maxCleanKeys 每个匹配模式单次清理最多检查的 key 数量，避免夜间任务长时间占用 Redis
+const maxCleanKeys = 100000
+
 // CleanExpiredTokens 清理Redis中过期的token相关缓存
-func CleanExpiredTokens() {
-	ctx := context.Background()
+func CleanExpiredTokens(ctx context.Context) (string, error) {
 	// 清理权限缓存（已有TTL，这里做兜底清理）
-	keys, _ := database.RDB.Keys(ctx, "user:permissions:*").Result()
-	cleaned := 0
-	for _, key := range keys {
-		ttl, _ := database.RDB.TTL(ctx, key).Result()
-		if ttl < 0 {
-			database.RDB.Del(ctx, key)
-			cleaned++
-		}
+	cleaned, err := cleanKeysWithoutTTL(ctx, "user:permissions:*")
+	if err != nil {
+		return "", err
 	}
 	if cleaned > 0 {
-		log.Printf("[Cron] 清理过期权限缓存: %d 条", cleaned)
+		return fmt.Sprintf("清理过期权限缓存: %d 条", cleaned), nil
+	}
+	return "", nil
+}
+
+// CleanTempFiles 清理存储临时区中超过存活时间的文件
+// 存活时间（小时）读取 config_webs 中的 temp_file_max_hours，默认24
+func CleanTempFiles(ctx context.Context) (string, error) {
+	if storage.Default == nil {
+		return "", fmt.Errorf("存储未初始化")
 	}
+	hours := tempFileMaxHours()
+	deleted, err := storage.DeleteOlderThan(storage.Default, storage.TempDir, time.Now().Add(-time.Duration(hours)*time.Hour))
+	if err != nil {
+		return fmt.Sprintf("已清理临时文件: %d 个", deleted), err
+	}
+	return fmt.Sprintf("清理%d小时前的临时文件: %d 个", hours, deleted), nil
 }
 
-// CleanTempFiles 清理临时上传文件（超过24小时的临时文件）
-func CleanTempFiles() {
-	// 清理uploads/tmp目录下超过24小时的文件
-	log.Println("[Cron] 临时文件清理任务执行")
+func tempFileMaxHours() int {
+	var web model.ConfigWeb
+	if err := database.DB.Where("code = ? AND tenant_id = 0", "temp_file_max_hours").First(&web).Error; err == nil {
+		var v int
+		if n, _ := fmt.Sscanf(web.Value, "%d", &v); n == 1 && v > 0 {
+			return v
+		}
+	}
+	return 24
 }
 
 // CleanExpiredLocks 清理过期的登录锁定记录
-func CleanExpiredLocks() {
-	ctx := context.Background()
+func CleanExpiredLocks(ctx context.Context) (string, error) {
 	patterns := []string{"login:fail:*", "login:lock:*", "ratelimit:*"}
 	cleaned := 0
 	for _, pattern := range patterns {
-		keys, _ := database.RDB.Keys(ctx, pattern).Result()
-		for _, key := range keys {
-			ttl, _ := database.RDB.TTL(ctx, key).Result()
-			if ttl < 0 {
-				database.RDB.Del(ctx, key)
-				cleaned++
-			}
+		n, err := cleanKeysWithoutTTL(ctx, pattern)
+		cleaned += n
+		if err != nil {
+			return fmt.Sprintf("清理过期锁定/限流记录: %d 条", cleaned), err
 		}
 	}
 	if cleaned > 0 {
-		log.Printf("[Cron] 清理过期锁定/限流记录: %d 条", cleaned)
+		return fmt.Sprintf("清理过期锁定/限流记录: %d 条", cleaned), nil
+	}
+	return "", nil
+}
+
+// cleanKeysWithoutTTL 分批扫描匹配的 key，删除未设置过期时间的残留数据
+func cleanKeysWithoutTTL(ctx context.Context, pattern string) (int, error) {
+	cleaned := 0
+	_, err := database.ScanKeys(ctx, database.ScanOptions{Match: pattern, MaxKeys: maxCleanKeys}, func(keys []string) error {
+		pipe := database.RDB.Pipeline()
+		ttls := make([]*redis.DurationCmd, len(keys))
+		for i, key := range keys {
+			ttls[i] = pipe.TTL(ctx, key)
+		}
+		if _, err := pipe.Exec(ctx); err != nil && err != redis.Nil {
+			return err
+		}
+
+		var stale []string
+		for i, cmd := range ttls {
+			// -1 表示未设置过期时间（-2 为已不存在）
+			if cmd.Val() == -1 {
+				stale = append(stale, keys[i])
+			}
+		}
+		if len(stale) == 0 {
+			return nil
+		}
+		n, err := database.RDB.Del(ctx, stale...).Result()
+		cleaned += int(n)
+		return err
+	})
+	return cleaned, err
+}
+
+// CleanOldOperationLogs 按保留天数清理操作、登录、邮件、短信日志
+// 保留天数读取日志配置 log_{operation,login,email,sms}_retention_days，默认30天，0=永久保留
+func CleanOldOperationLogs(ctx context.Context) (string, error) {
+	return cleanLogTables(ctx, operationLogTables)
+}
+
+// LoadJobs 加载数据库中所有启用的定时任务
+func LoadJobs() {
+	var jobs []model.Crontab
+	if err := database.DB.Where("status = ?", 1).Order("sort ASC, id ASC").Find(&jobs).Error; err != nil {
+		log.Printf("[Cron] 加载定时任务失败: %v", err)
+		return
+	}
+	for i := range jobs {
+		if err := schedule(&jobs[i]); err != nil {
+			log.Printf("[Cron] 注册任务[%d]%s失败: %v", jobs[i].ID, jobs[i].Name, err)
+		}
+	}
+	log.Printf("[Cron] 已加载数据库定时任务: %d 个", len(entries))
+}
+
+// Reload 按最新配置重新注册任务（新增/修改后调用），未启用或已达执行上限的任务仅移除
+// 变更会通知到其他节点
+func Reload(job *model.Crontab) error {
+	if err := schedule(job); err != nil {
+		return err
+	}
+	publish(job.ID)
+	return nil
+}
+
+// Remove 从调度器中移除任务（删除/停用后调用），变更会通知到其他节点
+func Remove(id uint) {
+	unschedule(id)
+	publish(id)
+}
+
+// schedule 在本节点注册任务
+func schedule(job *model.Crontab) error {
+	unschedule(job.ID)
+	if C == nil || job.Status != 1 || reachedMaximums(job) {
+		job.NextRunAt = nil
+		updateNextRunAt(job.ID, nil)
+		return nil
+	}
+
+	sched, err := ParseExpression(job.Expression)
+	if err != nil {
+		return err
+	}
+
+	// 先在调度时刻抢占租约（多节点去重），再交给重叠策略包装后的任务执行
+	id := job.ID
+	ttl := leaseTTL(job)
+	wrapped := cron.NewChain(overlapWrappers(job.Overlap)...).Then(cron.FuncJob(func() { run(id, TriggerSchedule) }))
+	mu.Lock()
+	entries[id] = C.Schedule(sched, cron.FuncJob(func() {
+		if claimTick(id, ttl) {
+			wrapped.Run()
+		}
+	}))
+	mu.Unlock()
+
+	next := sched.Next(time.Now())
+	job.NextRunAt = &next
+	updateNextRunAt(id, &next)
+	return nil
+}
+
+// unschedule 从本节点调度器中移除任务
+func unschedule(id uint) {
+	if C == nil {
+		return
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if entryID, ok := entries[id]; ok {
+		C.Remove(entryID)
+		delete(entries, id)
+	}
+}
+
+// Pause 暂停任务：停用并立即从调度器移除
+func Pause(id uint) error {
+	if err := database.DB.Model(&model.Crontab{}).Where("id = ?", id).Update("status", 0).Error; err != nil {
+		return err
+	}
+	Remove(id)
+	updateNextRunAt(id, nil)
+	return nil
+}
+
+// Resume 恢复任务：启用并立即注册到调度器
+func Resume(id uint) error {
+	var job model.Crontab
+	if err := database.DB.First(&job, id).Error; err != nil {
+		return err
+	}
+	job.Status = 1
+	if err := database.DB.Model(&job).Update("status", 1).Error; err != nil {
+		return err
+	}
+	return Reload(&job)
+}
+
+// RunNow 立即异步执行一次任务（不受暂停状态影响），执行记录与定时触发一致
+// 重叠策略为跳过且任务正在执行时返回 ErrRunning
+func RunNow(id uint) error {
+	var job model.Crontab
+	if err := database.DB.First(&job, id).Error; err != nil {
+		return fmt.Errorf("定时任务不存在")
+	}
+	if job.Overlap == model.CrontabOverlapSkip && isRunning(id) {
+		return ErrRunning
 	}
+	go run(id, TriggerManual)
+	return nil
 }
 
-// CleanOldOperationLogs 清理30天前的操作日志
-func CleanOldOperationLogs() {
-	threshold := time.Now().AddDate(0, 0, -30)
-	result := database.DB.Where("created_at < ?", threshold).Delete(&model.OperationLog{})
-	if result.RowsAffected > 0 {
-		log.Printf("[Cron] 清理30天前操作日志: %d 条", result.RowsAffected)
+// run 执行数据库定义的任务，并维护执行次数与执行时间
+func run(id uint, trigger string) {
+	var job model.Crontab
+	if err := database.DB.First(&job, id).Error; err != nil {
+		unschedule(id)
+		return
+	}
+	if trigger == TriggerSchedule && (job.Status != 1 || reachedMaximums(&job)) {
+		unschedule(id)
+		updateNextRunAt(id, nil)
+		return
+	}
+
+	release, err := acquireRunning(&job)
+	if err != nil {
+		log.Printf("[Cron] 任务[%d]%s 跳过本次执行: %v", job.ID, job.Name, err)
+		return
+	}
+	defer release()
+
+	startedAt := time.Now()
+	output, attempts, err := executeWithRetry(&job)
+	recordRun(&job, trigger, attempts, startedAt, output, err)
+	if err != nil {
+		log.Printf("[Cron] 任务[%d]%s 执行失败: %v", job.ID, job.Name, err)
+	} else if output != "" {
+		log.Printf("[Cron] 任务[%d]%s: %s", job.ID, job.Name, output)
 	}
 
-	// 清理30天前的登录日志
-	result2 := database.DB.Where("created_at < ?", threshold).Delete(&model.LoginLog{})
-	if result2.RowsAffected > 0 {
-		log.Printf("[Cron] 清理30天前登录日志: %d 条", result2.RowsAffected)
+	now := time.Now()
+	updates := map[string]interface{}{
+		"executes":    gorm.Expr("executes + 1"),
+		"last_run_at": now,
 	}
+	job.Executes++
+	if reachedMaximums(&job) {
+		// 达到最大执行次数后自动停止
+		unschedule(id)
+		updates["next_run_at"] = nil
+		log.Printf("[Cron] 任务[%d]%s 已达最大执行次数%d，停止调度", job.ID, job.Name, job.Maximums)
+	} else if next := nextRunAt(id); next != nil {
+		updates["next_run_at"] = next
+	}
+	database.DB.Model(&model.Crontab{}).Where("id = ?", id).Updates(updates)
+}
+
+// reachedMaximums 是否已达到最大执行次数（0=不限）
+func reachedMaximums(job *model.Crontab) bool {
+	return job.Maximums > 0 && job.Executes >= job.Maximums
 }
 
+func nextRunAt(id uint) *time.Time {
+	mu.Lock()
+	entryID, ok := entries[id]
+	mu.Unlock()
+	if !ok {
+		return nil
+	}
+	entry := C.Entry(entryID)
+	if !entry.Valid() {
+		return nil
+	}
+	next := entry.Schedule.Next(time.Now())
+	return &next
+}
+
+func updateNextRunAt(id uint, next *time.Time) {
+	if id == 0 {
+		return
+	}
+	database.DB.Model(&model.Crontab{}).Where("id = ?", id).Update("next_run_at", next)
+}
 
-// ListJobs 列出所有定时任务
+// ListJobs 列出本节点调度器中所有已注册的任务
 func ListJobs() []map[string]interface{} {
 	if C == nil {
 		return nil
 	}
-	entries := C.Entries()
+	mu.Lock()
+	defer mu.Unlock()
+	runningMu.Lock()
+	defer runningMu.Unlock()
 	jobs := make([]map[string]interface{}, 0, len(entries))
-	for _, entry := range entries {
+	for id, entryID := range entries {
+		entry := C.Entry(entryID)
 		jobs = append(jobs, map[string]interface{}{
-			"id":        fmt.Sprintf("%d", entry.ID),
-			"next_time": entry.Next.Format("2006-01-02 15:04:05"),
-			"prev_time": entry.Prev.Format("2006-01-02 15:04:05"),
+			"crontab_id": id,
+			"running":    running[id],
+			"next_time":  entry.Next.Format("2006-01-02 15:04:05"),
+			"prev_time":  entry.Prev.Format("2006-01-02 15:04:05"),
 		})
 	}
 	return jobs
@@ -0,0 +1,185 @@
+package crontab
+
+import (
+	"adcms/internal/model"
+	"adcms/pkg/database"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// HTTPContent HTTP 回调任务的请求定义，以 JSON 存于 Crontab.Content
+type HTTPContent struct {
+	Method  string            `json:"method"`
+	URL     string            `json:"url"`
+	Headers map[string]string `json:"headers"`
+	Body    string            `json:"body"`
+}
+
+var httpClient = &http.Client{Timeout: 30 * time.Second}
+
+// maxResponseBody HTTP 回调记录的响应体上限
+const maxResponseBody = 64 * 1024
+
+var allowedMethods = map[string]bool{
+	http.MethodGet:    true,
+	http.MethodPost:   true,
+	http.MethodPut:    true,
+	http.MethodPatch:  true,
+	http.MethodDelete: true,
+}
+
+// SQL 维护语句白名单：仅允许表优化/分析，以及对日志类表带条件的删除
+var (
+	sqlTableList   = "`?\\w+`?(?:\\s*,\\s*`?\\w+`?)*"
+	sqlOptimizeRe  = regexp.MustCompile(`(?i)^(?:OPTIMIZE|ANALYZE)\s+TABLE\s+` + sqlTableList + `$`)
+	sqlDeleteRe    = regexp.MustCompile("(?i)^DELETE\\s+FROM\\s+`?(\\w+)`?\\s+WHERE\\s+\\S.*$")
+	sqlDeleteTable = map[string]bool{
+		"operation_logs": true,
+		"login_logs":     true,
+		"email_logs":     true,
+		"sms_logs":       true,
+		"notifications":  true,
+		"crontab_logs":   true,
+	}
+)
+
+// Validate 校验任务的表达式、类型与命令，在保存前调用以拒绝无法执行的任务
+func Validate(job *model.Crontab) error {
+	if _, err := ParseExpression(job.Expression); err != nil {
+		return err
+	}
+	if err := validatePolicy(job); err != nil {
+		return err
+	}
+	switch job.Type {
+	case model.CrontabTypeBuiltin:
+		if _, ok := Lookup(job.Command); !ok {
+			return fmt.Errorf("未注册的内置任务: %s", job.Command)
+		}
+	case model.CrontabTypeHTTP:
+		if _, err := parseHTTPContent(job.Content); err != nil {
+			return err
+		}
+	case model.CrontabTypeSQL:
+		if _, err := normalizeSQL(job.Command); err != nil {
+			return err
+		}
+	default:
+		return fmt.Errorf("不支持的任务类型: %d", job.Type)
+	}
+	return nil
+}
+
+// execute 按任务类型执行一次任务
+func execute(ctx context.Context, job *model.Crontab) (string, error) {
+	switch job.Type {
+	case model.CrontabTypeBuiltin:
+		fn, ok := Lookup(job.Command)
+		if !ok {
+			return "", fmt.Errorf("未注册的内置任务: %s", job.Command)
+		}
+		return fn(ctx)
+	case model.CrontabTypeHTTP:
+		return runHTTP(ctx, job.Content)
+	case model.CrontabTypeSQL:
+		return runSQL(ctx, job.Command)
+	default:
+		return "", fmt.Errorf("不支持的任务类型: %d", job.Type)
+	}
+}
+
+func parseHTTPContent(content string) (*HTTPContent, error) {
+	var req HTTPContent
+	if err := json.Unmarshal([]byte(content), &req); err != nil {
+		return nil, errors.New("HTTP任务内容须为JSON: {\"method\",\"url\",\"headers\",\"body\"}")
+	}
+	req.Method = strings.ToUpper(strings.TrimSpace(req.Method))
+	if req.Method == "" {
+		req.Method = http.MethodGet
+	}
+	if !allowedMethods[req.Method] {
+		return nil, fmt.Errorf("不支持的请求方法: %s", req.Method)
+	}
+	u, err := url.Parse(req.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, fmt.Errorf("请求地址无效: %s", req.URL)
+	}
+	return &req, nil
+}
+
+func runHTTP(ctx context.Context, content string) (string, error) {
+	def, err := parseHTTPContent(content)
+	if err != nil {
+		return "", err
+	}
+
+	var body io.Reader
+	if def.Body != "" {
+		body = strings.NewReader(def.Body)
+	}
+	req, err := http.NewRequestWithContext(ctx, def.Method, def.URL, body)
+	if err != nil {
+		return "", err
+	}
+	for k, v := range def.Headers {
+		req.Header.Set(k, v)
+	}
+	if def.Body != "" && req.Header.Get("Content-Type") == "" {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	data, _ := io.ReadAll(io.LimitReader(resp.Body, maxResponseBody))
+	output := fmt.Sprintf("HTTP %d\n%s", resp.StatusCode, data)
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return output, fmt.Errorf("响应状态码异常: %d", resp.StatusCode)
+	}
+	return output, nil
+}
+
+// normalizeSQL 去除首尾空白与结尾分号，并校验语句是否在白名单内
+func normalizeSQL(stmt string) (string, error) {
+	stmt = strings.TrimSpace(stmt)
+	stmt = strings.TrimSpace(strings.TrimSuffix(stmt, ";"))
+	if stmt == "" {
+		return "", errors.New("SQL语句不能为空")
+	}
+	if strings.ContainsAny(stmt, ";#") || strings.Contains(stmt, "--") || strings.Contains(stmt, "/*") {
+		return "", errors.New("SQL语句仅允许单条且不能包含注释")
+	}
+	if sqlOptimizeRe.MatchString(stmt) {
+		return stmt, nil
+	}
+	if m := sqlDeleteRe.FindStringSubmatch(stmt); m != nil {
+		if !sqlDeleteTable[strings.ToLower(m[1])] {
+			return "", fmt.Errorf("不允许删除该表数据: %s", m[1])
+		}
+		return stmt, nil
+	}
+	return "", errors.New("仅允许 OPTIMIZE/ANALYZE TABLE 或对日志表带条件的 DELETE 语句")
+}
+
+func runSQL(ctx context.Context, command string) (string, error) {
+	stmt, err := normalizeSQL(command)
+	if err != nil {
+		return "", err
+	}
+	result := database.DB.WithContext(ctx).Exec(stmt)
+	if result.Error != nil {
+		return "", result.Error
+	}
+	return fmt.Sprintf("影响行数: %d", result.RowsAffected), nil
+}
@@ -0,0 +1,88 @@
+package crontab
+
+import (
+	"adcms/internal/model"
+	"testing"
+)
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		job     model.Crontab
+		wantErr bool
+	}{
+		{
+			name: "registered builtin",
+			job:  model.Crontab{Type: model.CrontabTypeBuiltin, Command: "CleanExpiredLocks"},
+		},
+		{
+			name:    "unknown builtin",
+			job:     model.Crontab{Type: model.CrontabTypeBuiltin, Command: "rm -rf /"},
+			wantErr: true,
+		},
+		{
+			name: "http post",
+			job:  model.Crontab{Type: model.CrontabTypeHTTP, Content: `{"method":"post","url":"https://example.com/hook","body":"{}"}`},
+		},
+		{
+			name:    "http bad scheme",
+			job:     model.Crontab{Type: model.CrontabTypeHTTP, Content: `{"url":"file:///etc/passwd"}`},
+			wantErr: true,
+		},
+		{
+			name:    "http not json",
+			job:     model.Crontab{Type: model.CrontabTypeHTTP, Content: "https://example.com"},
+			wantErr: true,
+		},
+		{
+			name: "sql optimize",
+			job:  model.Crontab{Type: model.CrontabTypeSQL, Command: "OPTIMIZE TABLE operation_logs, `login_logs`;"},
+		},
+		{
+			name: "sql delete log table",
+			job:  model.Crontab{Type: model.CrontabTypeSQL, Command: "DELETE FROM sms_logs WHERE created_at < NOW() - INTERVAL 7 DAY"},
+		},
+		{
+			name:    "sql delete users",
+			job:     model.Crontab{Type: model.CrontabTypeSQL, Command: "DELETE FROM users WHERE id > 1"},
+			wantErr: true,
+		},
+		{
+			name:    "sql delete without where",
+			job:     model.Crontab{Type: model.CrontabTypeSQL, Command: "DELETE FROM login_logs"},
+			wantErr: true,
+		},
+		{
+			name:    "sql multiple statements",
+			job:     model.Crontab{Type: model.CrontabTypeSQL, Command: "ANALYZE TABLE users; DROP TABLE users"},
+			wantErr: true,
+		},
+		{
+			name:    "sql comment",
+			job:     model.Crontab{Type: model.CrontabTypeSQL, Command: "DELETE FROM login_logs WHERE 1=1 -- x"},
+			wantErr: true,
+		},
+		{
+			name:    "invalid expression",
+			job:     model.Crontab{Type: model.CrontabTypeBuiltin, Command: "CleanExpiredLocks", Expression: "0 2 * * *"},
+			wantErr: true,
+		},
+		{
+			name:    "unknown type",
+			job:     model.Crontab{Type: 9},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		if tt.job.Expression == "" {
+			tt.job.Expression = "0 0 2 * * *"
+		}
+		t.Run(tt.name, func(t *testing.T) {
+			err := Validate(&tt.job)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,54 @@
+package crontab
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/robfig/cron/v3"
+)
+
+// parser 与调度器 cron.WithSeconds() 一致的秒级表达式解析器
+var parser = cron.NewParser(cron.Second | cron.Minute | cron.Hour | cron.Dom | cron.Month | cron.Dow | cron.Descriptor)
+
+// MaxPreview 预览执行时间的最大条数
+const MaxPreview = 50
+
+// ParseExpression 解析 cron 表达式（秒 分 时 日 月 周，或 @daily/@every 1h 等描述符）
+func ParseExpression(expr string) (cron.Schedule, error) {
+	expr = strings.TrimSpace(expr)
+	if expr == "" {
+		return nil, errors.New("cron表达式不能为空")
+	}
+	sched, err := parser.Parse(expr)
+	if err != nil {
+		return nil, fmt.Errorf("cron表达式无效（格式: 秒 分 时 日 月 周）: %v", err)
+	}
+	return sched, nil
+}
+
+// NextRuns 计算表达式从 from 之后的 n 次执行时间（服务器时区）
+func NextRuns(expr string, n int, from time.Time) ([]time.Time, error) {
+	sched, err := ParseExpression(expr)
+	if err != nil {
+		return nil, err
+	}
+	if n <= 0 {
+		n = 1
+	}
+	if n > MaxPreview {
+		n = MaxPreview
+	}
+	runs := make([]time.Time, 0, n)
+	t := from
+	for i := 0; i < n; i++ {
+		t = sched.Next(t)
+		if t.IsZero() {
+			// 表达式不会再触发（如 2 月 30 日）
+			break
+		}
+		runs = append(runs, t)
+	}
+	return runs, nil
+}
@@ -0,0 +1,52 @@
+package crontab
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseExpression(t *testing.T) {
+	valid := []string{"0 0 2 * * *", "*/10 * * * * *", "0 30 9 * * 1-5", "@daily", "@every 1h30m"}
+	for _, expr := range valid {
+		if _, err := ParseExpression(expr); err != nil {
+			t.Errorf("ParseExpression(%q) error = %v", expr, err)
+		}
+	}
+
+	invalid := []string{"", "0 2 * * *", "0 0 25 * * *", "every day", "0 0 2 * * * *"}
+	for _, expr := range invalid {
+		if _, err := ParseExpression(expr); err == nil {
+			t.Errorf("ParseExpression(%q) expected error", expr)
+		}
+	}
+}
+
+func TestNextRuns(t *testing.T) {
+	from := time.Date(2024, 1, 1, 1, 30, 0, 0, time.Local)
+	runs, err := NextRuns("0 0 2 * * *", 3, from)
+	if err != nil {
+		t.Fatalf("NextRuns() error = %v", err)
+	}
+	want := []time.Time{
+		time.Date(2024, 1, 1, 2, 0, 0, 0, time.Local),
+		time.Date(2024, 1, 2, 2, 0, 0, 0, time.Local),
+		time.Date(2024, 1, 3, 2, 0, 0, 0, time.Local),
+	}
+	if len(runs) != len(want) {
+		t.Fatalf("NextRuns() got %d runs, want %d", len(runs), len(want))
+	}
+	for i := range want {
+		if !runs[i].Equal(want[i]) {
+			t.Errorf("run[%d] = %v, want %v", i, runs[i], want[i])
+		}
+	}
+
+	runs, _ = NextRuns("@every 1m", MaxPreview+10, from)
+	if len(runs) != MaxPreview {
+		t.Errorf("NextRuns() should cap at %d, got %d", MaxPreview, len(runs))
+	}
+
+	if _, err := NextRuns("bad", 3, from); err == nil {
+		t.Error("NextRuns() expected error for invalid expression")
+	}
+}
@@ -0,0 +1,80 @@
+package crontab
+
+import (
+	"adcms/internal/model"
+	"adcms/pkg/database"
+	"adcms/pkg/ldapauth"
+	"adcms/pkg/session"
+	"context"
+	"fmt"
+	"strings"
+)
+
+// SyncLDAPAccounts 同步目录账号状态：已绑定目录身份的启用用户，在目录中被停用或删除后禁用本地账号并强制下线
+func SyncLDAPAccounts(ctx context.Context) (string, error) {
+	var configs []model.LDAPConfig
+	if err := database.DB.WithContext(ctx).Where("status = 1").Order("id ASC").Find(&configs).Error; err != nil {
+		return "", err
+	}
+
+	var parts []string
+	var failed []string
+	for i := range configs {
+		if err := ctx.Err(); err != nil {
+			return strings.Join(parts, "，"), err
+		}
+		cfg := &configs[i]
+		disabled, err := syncLDAPConfig(ctx, cfg)
+		if err != nil {
+			failed = append(failed, fmt.Sprintf("%s: %v", cfg.Name, err))
+			continue
+		}
+		parts = append(parts, fmt.Sprintf("%s: 禁用 %d 个", cfg.Name, disabled))
+	}
+	if len(failed) > 0 {
+		return strings.Join(parts, "，"), fmt.Errorf("同步失败: %s", strings.Join(failed, "；"))
+	}
+	return strings.Join(parts, "，"), nil
+}
+
+func syncLDAPConfig(ctx context.Context, cfg *model.LDAPConfig) (int, error) {
+	var idents []model.UserIdentity
+	if err := database.DB.WithContext(ctx).Table("user_identities").
+		Select("user_identities.*").
+		Joins("JOIN users ON users.id = user_identities.user_id AND users.deleted_at IS NULL").
+		Where("user_identities.type = ? AND user_identities.provider_id = ? AND users.status = 1", model.IdentityLDAP, cfg.ID).
+		Find(&idents).Error; err != nil {
+		return 0, err
+	}
+	if len(idents) == 0 {
+		return 0, nil
+	}
+
+	subjects := make([]string, len(idents))
+	for i, ident := range idents {
+		subjects[i] = ident.Subject
+	}
+	entries, err := ldapauth.Lookup(cfg, subjects)
+	if err != nil {
+		return 0, err
+	}
+	// 一个都查不到多半是 Base DN 或过滤器配置错误，不批量禁用
+	if len(entries) == 0 {
+		return 0, fmt.Errorf("目录中未找到任何已绑定用户，请检查配置")
+	}
+
+	disabled := 0
+	for _, ident := range idents {
+		if entry, ok := entries[ident.Subject]; ok && !entry.Disabled {
+			continue
+		}
+		if err := database.DB.WithContext(ctx).Model(&model.User{}).
+			Where("id = ?", ident.UserID).Update("status", 0).Error; err != nil {
+			return disabled, err
+		}
+		session.RevokeUser(ident.UserID, "")
+		session.BumpVersion(ident.UserID)
+		disabled++
+	}
+	return disabled, nil
+}
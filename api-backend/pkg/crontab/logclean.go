@@ -0,0 +1,158 @@
+package crontab
+
+import (
+	"adcms/pkg/database"
+	"adcms/pkg/logcfg"
+	"adcms/pkg/storage"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+)
+
+const (
+	logCleanBatch = 1000                   // 每批删除/归档的行数，避免长时间锁表
+	logCleanPause = 100 * time.Millisecond // 批次间隔，让出数据库给业务请求
+
+	// 日志含 IP、请求参数等敏感信息，归档写入不对外提供访问的日志目录，不能放在公开的上传存储中
+	logArchiveDir = "./logs/archive"
+)
+
+// logTable 按保留天数清理的日志表
+type logTable struct {
+	Type  string // 对应配置 log_{Type}_retention_days
+	Table string
+	Title string
+}
+
+var operationLogTables = []logTable{
+	{"operation", "operation_logs", "操作日志"},
+	{"login", "login_logs", "登录日志"},
+	{"email", "email_logs", "邮件日志"},
+	{"sms", "sms_logs", "短信日志"},
+}
+
+var crontabLogTable = logTable{"crontab", "crontab_logs", "任务执行记录"}
+
+// cleanLogTables 依次清理多张日志表，返回汇总输出
+func cleanLogTables(ctx context.Context, tables []logTable) (string, error) {
+	archive := logcfg.IsArchiveEnabled()
+	var parts []string
+	for _, t := range tables {
+		days := logcfg.RetentionDays(t.Type)
+		if days == 0 {
+			parts = append(parts, fmt.Sprintf("%s: 永久保留", t.Title))
+			continue
+		}
+		deleted, err := cleanLogTable(ctx, t.Table, time.Now().AddDate(0, 0, -days), archive)
+		parts = append(parts, fmt.Sprintf("%s(%d天): %d 条", t.Title, days, deleted))
+		if err != nil {
+			return strings.Join(parts, "，"), fmt.Errorf("清理%s失败: %w", t.Title, err)
+		}
+	}
+	return strings.Join(parts, "，"), nil
+}
+
+// cleanLogTable 分批删除 threshold 之前的日志；开启归档时先完整归档再删除，归档失败则不删除
+func cleanLogTable(ctx context.Context, table string, threshold time.Time, archive bool) (int64, error) {
+	var maxID uint
+	if err := database.DB.WithContext(ctx).Table(table).Where("created_at < ?", threshold).
+		Select("COALESCE(MAX(id), 0)").Scan(&maxID).Error; err != nil {
+		return 0, err
+	}
+	if maxID == 0 {
+		return 0, nil
+	}
+
+	if archive {
+		if err := archiveLogTable(ctx, table, threshold, maxID); err != nil {
+			return 0, fmt.Errorf("归档失败: %w", err)
+		}
+	}
+
+	var total int64
+	for {
+		if err := ctx.Err(); err != nil {
+			return total, err
+		}
+		result := database.DB.WithContext(ctx).
+			Exec(fmt.Sprintf("DELETE FROM `%s` WHERE id <= ? AND created_at < ? LIMIT ?", table), maxID, threshold, logCleanBatch)
+		if result.Error != nil {
+			return total, result.Error
+		}
+		total += result.RowsAffected
+		if result.RowsAffected < logCleanBatch {
+			return total, nil
+		}
+		time.Sleep(logCleanPause)
+	}
+}
+
+// archiveLogTable 将待清理的日志按 id 顺序分批导出为 gzip 压缩的 JSONL，写入本地归档目录
+func archiveLogTable(ctx context.Context, table string, threshold time.Time, maxID uint) error {
+	tmp, err := os.CreateTemp("", table+"-*.jsonl.gz")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	defer tmp.Close()
+
+	zw := gzip.NewWriter(tmp)
+	enc := json.NewEncoder(zw)
+	var lastID uint
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		var rows []map[string]interface{}
+		if err := database.DB.WithContext(ctx).Table(table).
+			Where("id > ? AND id <= ? AND created_at < ?", lastID, maxID, threshold).
+			Order("id ASC").Limit(logCleanBatch).Find(&rows).Error; err != nil {
+			return err
+		}
+		for _, row := range rows {
+			if err := enc.Encode(row); err != nil {
+				return err
+			}
+		}
+		if len(rows) < logCleanBatch {
+			break
+		}
+		lastID = toUint(rows[len(rows)-1]["id"])
+	}
+	if err := zw.Close(); err != nil {
+		return err
+	}
+	if _, err := tmp.Seek(0, 0); err != nil {
+		return err
+	}
+
+	filename := fmt.Sprintf("%s-%s.jsonl.gz", table, threshold.Format("20060102"))
+	_, err = storage.NewLocalStorage(logArchiveDir, "").UploadReader(tmp, filename, table)
+	return err
+}
+
+func toUint(v interface{}) uint {
+	switch n := v.(type) {
+	case int64:
+		return uint(n)
+	case uint64:
+		return uint(n)
+	case int32:
+		return uint(n)
+	case uint32:
+		return uint(n)
+	case int:
+		return uint(n)
+	case uint:
+		return n
+	case []byte:
+		var id uint
+		fmt.Sscanf(string(n), "%d", &id)
+		return id
+	}
+	return 0
+}
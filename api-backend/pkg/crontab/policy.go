@@ -0,0 +1,198 @@
+package crontab
+
+import (
+	"adcms/internal/model"
+	"adcms/pkg/database"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/robfig/cron/v3"
+)
+
+const (
+	defaultTimeout = 10 * time.Minute // 未设置超时时的单次执行上限
+	maxTimeout     = 24 * 60 * 60     // 超时上限（秒）
+	maxRetries     = 10
+
+	retryBaseDelay = 5 * time.Second // 首次重试间隔，之后每次翻倍
+	retryMaxDelay  = 5 * time.Minute
+
+	runningKeyPrefix = "cron:running:"
+)
+
+// ErrRunning 任务仍在执行且策略不允许重叠
+var ErrRunning = errors.New("任务正在执行中")
+
+// cronLogger 供 robfig/cron 任务包装器输出日志
+var cronLogger = cron.VerbosePrintfLogger(log.New(log.Writer(), "[Cron] ", log.LstdFlags))
+
+// releaseScript 仅当运行标记仍属于自己时才删除
+var releaseScript = redis.NewScript(`
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("DEL", KEYS[1])
+end
+return 0`)
+
+var (
+	runningMu sync.Mutex
+	running   = make(map[uint]int) // crontab ID -> 本节点执行中的数量
+)
+
+// validatePolicy 校验超时、重试与重叠策略配置
+func validatePolicy(job *model.Crontab) error {
+	if job.Timeout < 0 || job.Timeout > maxTimeout {
+		return fmt.Errorf("超时时间须在0~%d秒之间", maxTimeout)
+	}
+	if job.Retries < 0 || job.Retries > maxRetries {
+		return fmt.Errorf("重试次数须在0~%d之间", maxRetries)
+	}
+	switch job.Overlap {
+	case model.CrontabOverlapSkip, model.CrontabOverlapQueue, model.CrontabOverlapAllow:
+	default:
+		return fmt.Errorf("不支持的重叠策略: %d", job.Overlap)
+	}
+	return nil
+}
+
+// jobTimeout 单次执行超时
+func jobTimeout(job *model.Crontab) time.Duration {
+	if job.Timeout <= 0 {
+		return defaultTimeout
+	}
+	return time.Duration(job.Timeout) * time.Second
+}
+
+// backoff 第 n 次重试前的等待时间
+func backoff(n int) time.Duration {
+	d := retryBaseDelay
+	for i := 1; i < n && d < retryMaxDelay; i++ {
+		d *= 2
+	}
+	if d > retryMaxDelay {
+		d = retryMaxDelay
+	}
+	return d
+}
+
+// leaseTTL 一次调度（含全部重试）可能占用的最长时间，用作执行租约与运行标记的有效期
+func leaseTTL(job *model.Crontab) time.Duration {
+	ttl := jobTimeout(job) * time.Duration(job.Retries+1)
+	for n := 1; n <= job.Retries; n++ {
+		ttl += backoff(n)
+	}
+	return ttl + time.Minute
+}
+
+// overlapWrappers 按重叠策略返回本节点使用的任务包装器
+func overlapWrappers(overlap int8) []cron.JobWrapper {
+	wrappers := []cron.JobWrapper{cron.Recover(cronLogger)}
+	switch overlap {
+	case model.CrontabOverlapSkip:
+		wrappers = append(wrappers, cron.SkipIfStillRunning(cronLogger))
+	case model.CrontabOverlapQueue:
+		wrappers = append(wrappers, cron.DelayIfStillRunning(cronLogger))
+	}
+	return wrappers
+}
+
+// acquireRunning 按重叠策略在 Redis 中登记运行标记，保证多节点间同样不重叠
+// 返回的 release 用于执行结束后释放标记
+func acquireRunning(job *model.Crontab) (release func(), err error) {
+	runningMu.Lock()
+	running[job.ID]++
+	runningMu.Unlock()
+	local := func() {
+		runningMu.Lock()
+		if running[job.ID]--; running[job.ID] <= 0 {
+			delete(running, job.ID)
+		}
+		runningMu.Unlock()
+	}
+
+	if job.Overlap == model.CrontabOverlapAllow || database.RDB == nil {
+		return local, nil
+	}
+
+	ctx := context.Background()
+	key := fmt.Sprintf("%s%d", runningKeyPrefix, job.ID)
+	token := fmt.Sprintf("%s:%d", NodeID, time.Now().UnixNano())
+	ttl := leaseTTL(job)
+	deadline := time.Now().Add(ttl)
+	for {
+		ok, err := database.RDB.SetNX(ctx, key, token, ttl).Result()
+		if err != nil {
+			local()
+			return nil, err
+		}
+		if ok {
+			break
+		}
+		if job.Overlap == model.CrontabOverlapSkip || time.Now().After(deadline) {
+			local()
+			return nil, ErrRunning
+		}
+		time.Sleep(time.Second)
+	}
+
+	return func() {
+		releaseScript.Run(ctx, database.RDB, []string{key}, token)
+		local()
+	}, nil
+}
+
+// isRunning 任务是否在任一节点执行中（仅对跳过/排队策略可靠）
+func isRunning(id uint) bool {
+	runningMu.Lock()
+	n := running[id]
+	runningMu.Unlock()
+	if n > 0 {
+		return true
+	}
+	if database.RDB == nil {
+		return false
+	}
+	exists, _ := database.RDB.Exists(context.Background(), fmt.Sprintf("%s%d", runningKeyPrefix, id)).Result()
+	return exists > 0
+}
+
+// executeWithTimeout 带超时执行一次；任务未响应取消时也按超时返回，避免调度永久挂起
+func executeWithTimeout(job *model.Crontab, timeout time.Duration) (string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	type result struct {
+		output string
+		err    error
+	}
+	ch := make(chan result, 1)
+	go func() {
+		output, err := execute(ctx, job)
+		ch <- result{output, err}
+	}()
+
+	select {
+	case r := <-ch:
+		return r.output, r.err
+	case <-ctx.Done():
+		return "", fmt.Errorf("执行超时（%s）", timeout)
+	}
+}
+
+// executeWithRetry 执行任务，失败后按指数退避重试
+func executeWithRetry(job *model.Crontab) (output string, attempts int, err error) {
+	timeout := jobTimeout(job)
+	for attempts = 1; ; attempts++ {
+		output, err = executeWithTimeout(job, timeout)
+		if err == nil || attempts > job.Retries {
+			return output, attempts, err
+		}
+		delay := backoff(attempts)
+		log.Printf("[Cron] 任务[%d]%s 第%d次执行失败，%s后重试: %v", job.ID, job.Name, attempts, delay, err)
+		time.Sleep(delay)
+	}
+}
@@ -0,0 +1,55 @@
+package crontab
+
+import (
+	"adcms/internal/model"
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestBackoff(t *testing.T) {
+	want := []time.Duration{5 * time.Second, 10 * time.Second, 20 * time.Second, 40 * time.Second}
+	for i, w := range want {
+		if got := backoff(i + 1); got != w {
+			t.Errorf("backoff(%d) = %v, want %v", i+1, got, w)
+		}
+	}
+	if got := backoff(20); got != retryMaxDelay {
+		t.Errorf("backoff(20) = %v, want %v", got, retryMaxDelay)
+	}
+}
+
+func TestLeaseTTL(t *testing.T) {
+	job := &model.Crontab{Timeout: 60, Retries: 2}
+	// 3 次执行 + 5s + 10s 退避 + 1 分钟余量
+	want := 3*time.Minute + 15*time.Second + time.Minute
+	if got := leaseTTL(job); got != want {
+		t.Errorf("leaseTTL() = %v, want %v", got, want)
+	}
+	if got := jobTimeout(&model.Crontab{}); got != defaultTimeout {
+		t.Errorf("jobTimeout() = %v, want %v", got, defaultTimeout)
+	}
+}
+
+func TestExecuteWithTimeout(t *testing.T) {
+	Register("testSleep", "test", func(ctx context.Context) (string, error) {
+		time.Sleep(time.Second) // 不响应取消
+		return "done", nil
+	})
+	Register("testFail", "test", func(ctx context.Context) (string, error) {
+		return "", errors.New("boom")
+	})
+
+	job := &model.Crontab{Type: model.CrontabTypeBuiltin, Command: "testSleep"}
+	_, err := executeWithTimeout(job, 50*time.Millisecond)
+	if err == nil || !strings.Contains(err.Error(), "超时") {
+		t.Errorf("executeWithTimeout() error = %v, want timeout", err)
+	}
+
+	job = &model.Crontab{Type: model.CrontabTypeBuiltin, Command: "testFail"}
+	if _, attempts, err := executeWithRetry(job); err == nil || attempts != 1 {
+		t.Errorf("executeWithRetry() attempts = %d, err = %v", attempts, err)
+	}
+}
@@ -0,0 +1,50 @@
+package crontab
+
+import (
+	"context"
+	"sort"
+	"sync"
+)
+
+// Func 内置任务函数，返回的文本作为本次执行输出
+type Func func(ctx context.Context) (string, error)
+
+// Builtin 已注册的内置任务
+type Builtin struct {
+	Name  string `json:"name"`
+	Title string `json:"title"`
+	fn    Func
+}
+
+var (
+	registryMu sync.RWMutex
+	registry   = make(map[string]Builtin)
+)
+
+// Register 注册内置任务，Crontab.Command 通过名称引用
+// 重复注册同名任务会覆盖之前的实现
+func Register(name, title string, fn Func) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	registry[name] = Builtin{Name: name, Title: title, fn: fn}
+}
+
+// Lookup 按名称查找内置任务
+func Lookup(name string) (Func, bool) {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+	b, ok := registry[name]
+	return b.fn, ok
+}
+
+// Builtins 列出所有已注册的内置任务（按名称排序）
+func Builtins() []Builtin {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+	list := make([]Builtin, 0, len(registry))
+	for _, b := range registry {
+		list = append(list, b)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Name < list[j].Name })
+	return list
+}
@@ -0,0 +1,69 @@
+package crontab
+
+import (
+	"adcms/internal/model"
+	"adcms/pkg/database"
+	"context"
+	"fmt"
+	"os"
+	"time"
+)
+
+const (
+	maxLogOutput = 8000 // 执行输出保留的最大字符数
+	maxLogError  = 1000 // 错误信息保留的最大字符数
+)
+
+// 执行触发方式
+const (
+	TriggerSchedule = "schedule" // 定时触发
+	TriggerManual   = "manual"   // 手动触发
+)
+
+// NodeID 当前进程的节点标识（主机名:PID）
+var NodeID = func() string {
+	host, err := os.Hostname()
+	if err != nil || host == "" {
+		host = "unknown"
+	}
+	return fmt.Sprintf("%s:%d", host, os.Getpid())
+}()
+
+// recordRun 写入一条执行记录
+func recordRun(job *model.Crontab, trigger string, attempts int, startedAt time.Time, output string, runErr error) {
+	endedAt := time.Now()
+	entry := model.CrontabLog{
+		TenantID:  job.TenantID,
+		CrontabID: job.ID,
+		Name:      job.Name,
+		Node:      NodeID,
+		Trigger:   trigger,
+		Attempts:  attempts,
+		Status:    1,
+		Output:    truncate(output, maxLogOutput),
+		StartedAt: startedAt,
+		EndedAt:   endedAt,
+		Duration:  endedAt.Sub(startedAt).Milliseconds(),
+		CreatedAt: endedAt,
+	}
+	if runErr != nil {
+		entry.Status = 0
+		entry.Error = truncate(runErr.Error(), maxLogError)
+	}
+	database.DB.Create(&entry)
+}
+
+// truncate 按字符截断字符串，结果（含截断标记）不超过 max 个字符
+func truncate(s string, max int) string {
+	const mark = "...(已截断)"
+	r := []rune(s)
+	if len(r) <= max {
+		return s
+	}
+	return string(r[:max-len([]rune(mark))]) + mark
+}
+
+// CleanCrontabLogs 按保留天数（log_crontab_retention_days）清理定时任务执行记录
+func CleanCrontabLogs(ctx context.Context) (string, error) {
+	return cleanLogTables(ctx, []logTable{crontabLogTable})
+}
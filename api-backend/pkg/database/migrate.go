@@ -36,6 +36,15 @@ func AutoMigrate() error {
 		&model.Site{},
 		&model.Link{},
 		&model.Crontab{},
+		&model.CrontabLog{},
+		&model.JWTKey{},
+		&model.TOTPRecoveryCode{},
+		&model.PasswordHistory{},
+		&model.WebAuthnCredential{},
+		&model.OIDCProvider{},
+		&model.UserIdentity{},
+		&model.LDAPConfig{},
+		&model.APIKey{},
 		&model.City{},
 	)
 }
@@ -85,6 +94,8 @@ func InitData() error {
 		Status:      1,
 		LastLoginAt: &now,
 		IsAdmin:     2, // 超级管理员
+		// 初始密码为公开的默认值，首次登录须先修改
+		MustChangePassword: 1,
 	}
 	if err := DB.Create(&admin).Error; err != nil {
 		return err
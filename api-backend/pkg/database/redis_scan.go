@@ -0,0 +1,49 @@
+package database
+
+import (
+	"context"
+)
+
+const defaultScanCount = 500
+
+// ScanOptions 游标遍历参数
+type ScanOptions struct {
+	Match   string // 匹配模式，如 "login:fail:*"
+	Count   int64  // 每批 SCAN 的 COUNT 提示，默认 500
+	MaxKeys int    // 单次遍历最多处理的 key 数量，0=不限
+}
+
+// ScanKeys 使用 SCAN 游标分批遍历匹配的 key，避免 KEYS 阻塞 Redis
+// 每批调用一次 fn；fn 返回错误、ctx 取消或达到 MaxKeys 时停止，返回已处理的 key 数量
+func ScanKeys(ctx context.Context, opts ScanOptions, fn func(keys []string) error) (int, error) {
+	if opts.Count <= 0 {
+		opts.Count = defaultScanCount
+	}
+
+	var (
+		cursor uint64
+		total  int
+	)
+	for {
+		if err := ctx.Err(); err != nil {
+			return total, err
+		}
+		keys, next, err := RDB.Scan(ctx, cursor, opts.Match, opts.Count).Result()
+		if err != nil {
+			return total, err
+		}
+		if opts.MaxKeys > 0 && total+len(keys) > opts.MaxKeys {
+			keys = keys[:opts.MaxKeys-total]
+		}
+		if len(keys) > 0 {
+			if err := fn(keys); err != nil {
+				return total, err
+			}
+			total += len(keys)
+		}
+		cursor = next
+		if cursor == 0 || (opts.MaxKeys > 0 && total >= opts.MaxKeys) {
+			return total, nil
+		}
+	}
+}
@@ -5,7 +5,9 @@ import (
 	"adcms/pkg/database"
 	"adcms/pkg/logcfg"
 	"fmt"
+	"html"
 	"strconv"
+	"time"
 
 	"gopkg.in/gomail.v2"
 )
@@ -119,3 +121,52 @@ func SendResetCode(to, code string) error {
 	`, code)
 	return SendMail(to, subject, body)
 }
+
+// SendTOTPResetNotice 通知用户其两步验证已被管理员重置
+func SendTOTPResetNotice(to, username, reason string, at time.Time) error {
+	subject := "ADCMS 两步验证已重置"
+	body := fmt.Sprintf(`
+		<div style="max-width:500px;margin:0 auto;padding:20px;font-family:Arial,sans-serif;">
+			<h2 style="color:#1890ff;">ADCMS 安全提醒</h2>
+			<p>您好，%s：</p>
+			<p>您账号的两步验证（TOTP）已于 %s 由管理员重置，原有的动态验证码和恢复码均已失效，所有已登录设备已下线。</p>
+			<p>重置原因：%s</p>
+			<p style="color:#999;font-size:12px;margin-top:15px;">
+				请在下次登录后尽快重新绑定两步验证。<br/>
+				如非本人申请，请立即修改密码并联系管理员。
+			</p>
+		</div>
+	`, html.EscapeString(username), at.Format("2006-01-02 15:04:05"), html.EscapeString(reason))
+	return SendMail(to, subject, body)
+}
+
+// SendLoginAlert 新设备或异地登录提醒，revokeURL 为"不是我本人"的处理链接
+func SendLoginAlert(to, username, ip, location, device string, at time.Time, revokeURL string) error {
+	subject := "ADCMS 新设备登录提醒"
+	if location == "" {
+		location = "未知"
+	}
+	// 未配置站点公开地址时不附带链接，避免使用请求中可伪造的 Host 生成链接
+	revokeSection := `<p>如果不是您本人操作，请立即修改密码并在"登录设备"中注销可疑会话。</p>`
+	if revokeURL != "" {
+		revokeSection = fmt.Sprintf(`<p>如果不是您本人操作，请点击下方链接注销该账号的全部登录并作废当前密码，之后通过找回密码重新设置：</p>
+			<p><a href="%s" style="color:#ff4d4f;">这不是我本人登录</a></p>
+			<p style="color:#999;font-size:12px;margin-top:15px;">链接7天内有效。</p>`, html.EscapeString(revokeURL))
+	}
+	body := fmt.Sprintf(`
+		<div style="max-width:500px;margin:0 auto;padding:20px;font-family:Arial,sans-serif;">
+			<h2 style="color:#1890ff;">ADCMS 安全提醒</h2>
+			<p>您好，%s：</p>
+			<p>您的账号于 %s 在新的设备或地点登录：</p>
+			<ul style="color:#333;">
+				<li>IP：%s</li>
+				<li>地点：%s</li>
+				<li>设备：%s</li>
+			</ul>
+			<p>如果是您本人操作，请忽略此邮件。</p>
+			%s
+		</div>
+	`, html.EscapeString(username), at.Format("2006-01-02 15:04:05"), html.EscapeString(ip),
+		html.EscapeString(location), html.EscapeString(device), revokeSection)
+	return SendMail(to, subject, body)
+}
@@ -0,0 +1,174 @@
+package iplocate
+
+import (
+	"adcms/internal/model"
+	"adcms/pkg/database"
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"net"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// 离线 IP 归属地查询：
+//   - 数据文件每行一个 IPv4 段，格式为 "起始IP|结束IP|行政区划代码"，# 开头为注释
+//   - 行政区划代码对应 cities 表的 adcode，归属地名称取自 cities 表（省 + 市）
+//   - 内网、回环地址直接返回"内网"；未加载数据文件或未命中时返回空
+type ipRange struct {
+	start, end uint32
+	adcode     string
+}
+
+// Region IP 归属地
+type Region struct {
+	Adcode   string `json:"adcode"`
+	CityID   uint   `json:"city_id"`
+	Province string `json:"province"`
+	City     string `json:"city"`
+	Internal bool   `json:"internal"` // 内网地址
+}
+
+// String 归属地展示名称，如 "广东省 深圳市"
+func (r Region) String() string {
+	if r.Internal {
+		return "内网"
+	}
+	if r.City == "" || r.City == r.Province {
+		return r.Province
+	}
+	if r.Province == "" {
+		return r.City
+	}
+	return r.Province + " " + r.City
+}
+
+// Known 是否查到了归属地
+func (r Region) Known() bool {
+	return r.Internal || r.Adcode != ""
+}
+
+var (
+	mu     sync.RWMutex
+	ranges []ipRange
+
+	// adcode -> Region，cities 表数据基本不变，查询过的结果常驻内存
+	regionCache sync.Map
+)
+
+// Setup 加载数据文件，path 为空时仅识别内网地址
+func Setup(path string) error {
+	if path == "" {
+		return nil
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	loaded, err := parse(bufio.NewScanner(f))
+	if err != nil {
+		return err
+	}
+	mu.Lock()
+	ranges = loaded
+	mu.Unlock()
+	return nil
+}
+
+func parse(scanner *bufio.Scanner) ([]ipRange, error) {
+	var result []ipRange
+	line := 0
+	for scanner.Scan() {
+		line++
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		parts := strings.Split(text, "|")
+		if len(parts) != 3 {
+			return nil, fmt.Errorf("第%d行格式错误", line)
+		}
+		start, ok1 := ipv4ToUint(parts[0])
+		end, ok2 := ipv4ToUint(parts[1])
+		if !ok1 || !ok2 || start > end {
+			return nil, fmt.Errorf("第%d行 IP 段无效", line)
+		}
+		result = append(result, ipRange{start: start, end: end, adcode: strings.TrimSpace(parts[2])})
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].start < result[j].start })
+	return result, nil
+}
+
+func ipv4ToUint(s string) (uint32, bool) {
+	ip := net.ParseIP(strings.TrimSpace(s)).To4()
+	if ip == nil {
+		return 0, false
+	}
+	return binary.BigEndian.Uint32(ip), true
+}
+
+// adcodeOf 在已加载的 IP 段中查找行政区划代码
+func adcodeOf(ip string) string {
+	n, ok := ipv4ToUint(ip)
+	if !ok {
+		return ""
+	}
+	mu.RLock()
+	defer mu.RUnlock()
+	i := sort.Search(len(ranges), func(i int) bool { return ranges[i].start > n }) - 1
+	if i >= 0 && n <= ranges[i].end {
+		return ranges[i].adcode
+	}
+	return ""
+}
+
+// Lookup 查询 IP 归属地
+func Lookup(ip string) Region {
+	parsed := net.ParseIP(ip)
+	if parsed == nil {
+		return Region{}
+	}
+	if parsed.IsLoopback() || parsed.IsPrivate() || parsed.IsLinkLocalUnicast() {
+		return Region{Internal: true}
+	}
+	adcode := adcodeOf(ip)
+	if adcode == "" {
+		return Region{}
+	}
+	if cached, ok := regionCache.Load(adcode); ok {
+		return cached.(Region)
+	}
+	region := resolve(adcode)
+	regionCache.Store(adcode, region)
+	return region
+}
+
+// resolve 由 cities 表解析行政区划名称：命中的区划为市，向上一级为省
+func resolve(adcode string) Region {
+	region := Region{Adcode: adcode}
+	var city model.City
+	if err := database.DB.Where("adcode = ?", adcode).Order("level DESC").First(&city).Error; err != nil {
+		return region
+	}
+	region.CityID = city.ID
+	region.City = city.Name
+
+	// 逐级向上找到顶级（省级）区划
+	current := city
+	for i := 0; i < 3 && current.PID != 0; i++ {
+		var parent model.City
+		if err := database.DB.First(&parent, current.PID).Error; err != nil {
+			break
+		}
+		current = parent
+	}
+	region.Province = current.Name
+	return region
+}
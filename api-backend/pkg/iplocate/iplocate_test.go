@@ -0,0 +1,66 @@
+package iplocate
+
+import (
+	"bufio"
+	"strings"
+	"testing"
+)
+
+func TestParseAndSearch(t *testing.T) {
+	data := `# 测试数据
+203.0.113.0|203.0.113.255|440300
+198.51.100.0|198.51.100.127|110100
+`
+	loaded, err := parse(bufio.NewScanner(strings.NewReader(data)))
+	if err != nil {
+		t.Fatalf("parse failed: %v", err)
+	}
+	ranges = loaded
+	t.Cleanup(func() { ranges = nil })
+
+	for ip, want := range map[string]string{
+		"203.0.113.0":    "440300",
+		"203.0.113.200":  "440300",
+		"198.51.100.1":   "110100",
+		"198.51.100.128": "",
+		"192.0.2.1":      "",
+		"2001:db8::1":    "",
+	} {
+		if got := adcodeOf(ip); got != want {
+			t.Errorf("adcodeOf(%s) = %q, want %q", ip, got, want)
+		}
+	}
+
+	for _, bad := range []string{"1.1.1.1|1.1.1.0|110100", "1.1.1.1|110100", "x|1.1.1.1|110100"} {
+		if _, err := parse(bufio.NewScanner(strings.NewReader(bad))); err == nil {
+			t.Errorf("parse accepted %q", bad)
+		}
+	}
+}
+
+func TestInternal(t *testing.T) {
+	for _, ip := range []string{"127.0.0.1", "10.1.2.3", "192.168.0.8", "::1"} {
+		if r := Lookup(ip); !r.Internal || r.String() != "内网" {
+			t.Errorf("Lookup(%s) = %+v, want internal", ip, r)
+		}
+	}
+	if r := Lookup("not-an-ip"); r.Known() {
+		t.Errorf("invalid ip resolved: %+v", r)
+	}
+}
+
+func TestRegionString(t *testing.T) {
+	for _, tc := range []struct {
+		r    Region
+		want string
+	}{
+		{Region{Province: "广东省", City: "深圳市"}, "广东省 深圳市"},
+		{Region{Province: "北京市", City: "北京市"}, "北京市"},
+		{Region{City: "深圳市"}, "深圳市"},
+		{Region{}, ""},
+	} {
+		if got := tc.r.String(); got != tc.want {
+			t.Errorf("String() = %q, want %q", got, tc.want)
+		}
+	}
+}
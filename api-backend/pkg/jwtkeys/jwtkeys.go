@@ -0,0 +1,203 @@
+package jwtkeys
+
+import (
+	"adcms/internal/config"
+	"adcms/internal/model"
+	"adcms/pkg/database"
+	"adcms/pkg/utils"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// 非对称签名密钥管理：
+//   - 密钥保存在 jwt_keys 表，多节点共享；status=1 的密钥用于签名，轮换后旧密钥转为仅验签
+//   - 旧密钥在其签发的 token 全部过期后清理，轮换过程不会让已登录用户掉线
+//   - 切换算法前用 jwt.secret 签发的 HS256 token（无 kid）在过期前仍可验签
+const (
+	reloadInterval  = time.Minute      // 定期从数据库同步密钥（其他节点的轮换）
+	reloadThrottle  = 10 * time.Second // 遇到未知 kid 时重新加载的最小间隔
+	retireKeepExtra = time.Hour        // 旧密钥在 token 最长有效期之外额外保留的时间
+)
+
+// Provider 基于数据库密钥的 utils.KeyProvider 实现
+type Provider struct {
+	algorithm string
+
+	mu         sync.RWMutex
+	active     *Key
+	keys       map[string]*Key
+	loadedAt   time.Time
+	loadFunc   func() ([]model.JWTKey, error)
+	legacyHMAC bool
+}
+
+var current *Provider
+
+// Setup 按配置初始化签名密钥；HS256 时保持默认的 jwt.secret 签名
+func Setup() error {
+	alg := config.GlobalConfig.JWT.Algorithm
+	if alg == "" || alg == jwt.SigningMethodHS256.Alg() {
+		return nil
+	}
+	if _, err := signingMethod(alg); err != nil {
+		return err
+	}
+
+	p := &Provider{algorithm: alg, loadFunc: loadKeys, legacyHMAC: true}
+	if err := p.reload(); err != nil {
+		return err
+	}
+	if p.active == nil {
+		if _, err := Rotate(); err != nil {
+			return err
+		}
+		if err := p.reload(); err != nil {
+			return err
+		}
+	}
+	current = p
+	utils.SetKeyProvider(p)
+	go p.refreshLoop()
+	return nil
+}
+
+// Enabled 是否启用了非对称签名
+func Enabled() bool {
+	return current != nil
+}
+
+func (p *Provider) SigningKey() (string, jwt.SigningMethod, interface{}, error) {
+	if time.Since(p.loaded()) > reloadInterval {
+		p.reload()
+	}
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	if p.active == nil {
+		return "", nil, nil, errors.New("没有可用的签名密钥")
+	}
+	return p.active.Kid, p.active.method, p.active.private, nil
+}
+
+func (p *Provider) VerificationKey(kid, alg string) (interface{}, error) {
+	if kid == "" {
+		if p.legacyHMAC && alg == jwt.SigningMethodHS256.Alg() {
+			return []byte(config.GlobalConfig.JWT.Secret), nil
+		}
+		return nil, errors.New("缺少kid")
+	}
+
+	key := p.lookup(kid)
+	if key == nil && time.Since(p.loaded()) > reloadThrottle {
+		// 可能是其他节点刚轮换的新密钥
+		p.reload()
+		key = p.lookup(kid)
+	}
+	if key == nil {
+		return nil, fmt.Errorf("未知的签名密钥: %s", kid)
+	}
+	if key.method.Alg() != alg {
+		return nil, errors.New("签名算法不匹配")
+	}
+	return key.public, nil
+}
+
+func (p *Provider) lookup(kid string) *Key {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return p.keys[kid]
+}
+
+func (p *Provider) loaded() time.Time {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return p.loadedAt
+}
+
+// reload 从数据库加载全部有效密钥
+func (p *Provider) reload() error {
+	rows, err := p.loadFunc()
+	if err != nil {
+		log.Printf("[JWT] 加载签名密钥失败: %v", err)
+		return err
+	}
+	keys := make(map[string]*Key, len(rows))
+	var active *Key
+	for i := range rows {
+		key, err := parseKey(&rows[i])
+		if err != nil {
+			log.Printf("[JWT] 解析密钥 %s 失败: %v", rows[i].Kid, err)
+			continue
+		}
+		keys[key.Kid] = key
+		if rows[i].Status == model.JWTKeyActive && rows[i].Algorithm == p.algorithm {
+			if active == nil || key.CreatedAt.After(active.CreatedAt) {
+				active = key
+			}
+		}
+	}
+
+	p.mu.Lock()
+	p.keys = keys
+	p.active = active
+	p.loadedAt = time.Now()
+	p.mu.Unlock()
+	return nil
+}
+
+func (p *Provider) refreshLoop() {
+	ticker := time.NewTicker(reloadInterval)
+	defer ticker.Stop()
+	for range ticker.C {
+		p.reload()
+	}
+}
+
+func loadKeys() ([]model.JWTKey, error) {
+	var rows []model.JWTKey
+	err := database.DB.Order("id ASC").Find(&rows).Error
+	return rows, err
+}
+
+// Rotate 生成新的签名密钥，原签名密钥转为仅验签，并清理已无 token 使用的旧密钥
+func Rotate() (*model.JWTKey, error) {
+	alg := config.GlobalConfig.JWT.Algorithm
+	row, err := Generate(alg)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	tx := database.DB.Begin()
+	if err := tx.Model(&model.JWTKey{}).Where("status = ?", model.JWTKeyActive).
+		Updates(map[string]interface{}{"status": model.JWTKeyRetired, "retired_at": now}).Error; err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+	if err := tx.Create(row).Error; err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+	if err := tx.Commit().Error; err != nil {
+		return nil, err
+	}
+
+	// 旧密钥签发的 token 最长存活 access token 有效期
+	threshold := now.Add(-utils.AccessTokenTTL() - retireKeepExtra)
+	database.DB.Where("status = ? AND retired_at < ?", model.JWTKeyRetired, threshold).Delete(&model.JWTKey{})
+
+	if current != nil {
+		current.reload()
+	}
+	log.Printf("[JWT] 已轮换签名密钥: %s (%s)", row.Kid, row.Algorithm)
+	return row, nil
+}
+
+// List 列出所有密钥（不含私钥）
+func List() ([]model.JWTKey, error) {
+	return loadKeys()
+}
@@ -0,0 +1,99 @@
+package jwtkeys
+
+import (
+	"adcms/internal/config"
+	"adcms/internal/model"
+	"adcms/pkg/utils"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+func TestProviderSignAndVerify(t *testing.T) {
+	config.GlobalConfig = &config.Config{JWT: config.JWTConfig{Secret: "test-secret", ExpireHours: 1}}
+	defer utils.SetKeyProvider(utils.HMACKeyProvider{})
+
+	// 切换前签发的 HS256 token
+	legacy, err := utils.GenerateToken(1, 1, "admin", 2, 0, "")
+	if err != nil {
+		t.Fatalf("GenerateToken() error = %v", err)
+	}
+
+	for _, alg := range []string{"RS256", "EdDSA"} {
+		t.Run(alg, func(t *testing.T) {
+			old, err := Generate(alg)
+			if err != nil {
+				t.Fatalf("Generate() error = %v", err)
+			}
+			rows := []model.JWTKey{*old}
+			p := &Provider{algorithm: alg, legacyHMAC: true, loadFunc: func() ([]model.JWTKey, error) { return rows, nil }}
+			if err := p.reload(); err != nil {
+				t.Fatal(err)
+			}
+			utils.SetKeyProvider(p)
+
+			before, err := utils.GenerateToken(1, 1, "admin", 2, 0, "sid")
+			if err != nil {
+				t.Fatalf("GenerateToken() error = %v", err)
+			}
+
+			// 轮换：旧密钥转为仅验签，新密钥签名
+			next, _ := Generate(alg)
+			next.CreatedAt = old.CreatedAt.Add(time.Second)
+			rows[0].Status = model.JWTKeyRetired
+			rows = append(rows, *next)
+			p.reload()
+
+			after, _ := utils.GenerateToken(1, 1, "admin", 2, 0, "sid")
+			for name, token := range map[string]string{"before rotation": before, "after rotation": after, "legacy hs256": legacy} {
+				if _, err := utils.ParseToken(token); err != nil {
+					t.Errorf("ParseToken(%s) error = %v", name, err)
+				}
+			}
+
+			parsed, _, _ := jwt.NewParser().ParseUnverified(after, &utils.Claims{})
+			if parsed.Header["kid"] != next.Kid || parsed.Method.Alg() != alg {
+				t.Errorf("token header = %v, want kid %s alg %s", parsed.Header, next.Kid, alg)
+			}
+
+			// 旧密钥被清理后其签发的 token 失效
+			rows = rows[1:]
+			p.reload()
+			if _, err := utils.ParseToken(before); err == nil {
+				t.Error("token signed by removed key should be rejected")
+			}
+		})
+	}
+}
+
+func TestJWK(t *testing.T) {
+	for _, alg := range []string{"RS256", "EdDSA"} {
+		row, err := Generate(alg)
+		if err != nil {
+			t.Fatalf("Generate(%s) error = %v", alg, err)
+		}
+		key, err := parseKey(row)
+		if err != nil {
+			t.Fatalf("parseKey(%s) error = %v", alg, err)
+		}
+		jwk := key.toJWK()
+		if jwk.Kid != row.Kid || jwk.Alg != alg || jwk.Use != "sig" {
+			t.Errorf("toJWK() = %+v", jwk)
+		}
+		switch alg {
+		case "RS256":
+			if jwk.Kty != "RSA" || jwk.N == "" || jwk.E != "AQAB" {
+				t.Errorf("RSA JWK = %+v", jwk)
+			}
+		case "EdDSA":
+			if jwk.Kty != "OKP" || jwk.Crv != "Ed25519" || jwk.X == "" {
+				t.Errorf("OKP JWK = %+v", jwk)
+			}
+		}
+	}
+
+	if _, err := Generate("HS512"); err == nil {
+		t.Error("Generate(HS512) should fail")
+	}
+}
@@ -0,0 +1,165 @@
+package jwtkeys
+
+import (
+	"adcms/internal/model"
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"sort"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const rsaKeyBits = 2048
+
+// Key 解析后的签名密钥
+type Key struct {
+	Kid       string
+	CreatedAt time.Time
+	method    jwt.SigningMethod
+	private   crypto.Signer
+	public    crypto.PublicKey
+}
+
+// JWK 公钥的 JSON Web Key 表示
+type JWK struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	N   string `json:"n,omitempty"`   // RSA
+	E   string `json:"e,omitempty"`   // RSA
+	Crv string `json:"crv,omitempty"` // OKP
+	X   string `json:"x,omitempty"`   // OKP
+}
+
+func signingMethod(alg string) (jwt.SigningMethod, error) {
+	switch alg {
+	case jwt.SigningMethodRS256.Alg():
+		return jwt.SigningMethodRS256, nil
+	case jwt.SigningMethodEdDSA.Alg():
+		return jwt.SigningMethodEdDSA, nil
+	}
+	return nil, fmt.Errorf("不支持的JWT签名算法: %s", alg)
+}
+
+// Generate 生成指定算法的新密钥
+func Generate(alg string) (*model.JWTKey, error) {
+	if _, err := signingMethod(alg); err != nil {
+		return nil, err
+	}
+
+	var (
+		private crypto.Signer
+		err     error
+	)
+	if alg == jwt.SigningMethodRS256.Alg() {
+		private, err = rsa.GenerateKey(rand.Reader, rsaKeyBits)
+	} else {
+		_, private, err = ed25519.GenerateKey(rand.Reader)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("生成密钥失败: %w", err)
+	}
+
+	privDER, err := x509.MarshalPKCS8PrivateKey(private)
+	if err != nil {
+		return nil, err
+	}
+	pubDER, err := x509.MarshalPKIXPublicKey(private.Public())
+	if err != nil {
+		return nil, err
+	}
+
+	suffix := make([]byte, 4)
+	if _, err := rand.Read(suffix); err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	return &model.JWTKey{
+		Kid:        now.Format("20060102150405") + "-" + hex.EncodeToString(suffix),
+		Algorithm:  alg,
+		PrivateKey: string(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: privDER})),
+		PublicKey:  string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: pubDER})),
+		Status:     model.JWTKeyActive,
+		CreatedAt:  now,
+	}, nil
+}
+
+func parseKey(row *model.JWTKey) (*Key, error) {
+	method, err := signingMethod(row.Algorithm)
+	if err != nil {
+		return nil, err
+	}
+	block, _ := pem.Decode([]byte(row.PrivateKey))
+	if block == nil {
+		return nil, errors.New("私钥格式错误")
+	}
+	parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+	private, ok := parsed.(crypto.Signer)
+	if !ok {
+		return nil, errors.New("不支持的私钥类型")
+	}
+	switch private.(type) {
+	case *rsa.PrivateKey:
+		if method != jwt.SigningMethodRS256 {
+			return nil, errors.New("私钥与算法不匹配")
+		}
+	case ed25519.PrivateKey:
+		if method != jwt.SigningMethodEdDSA {
+			return nil, errors.New("私钥与算法不匹配")
+		}
+	default:
+		return nil, errors.New("不支持的私钥类型")
+	}
+	return &Key{
+		Kid:       row.Kid,
+		CreatedAt: row.CreatedAt,
+		method:    method,
+		private:   private,
+		public:    private.Public(),
+	}, nil
+}
+
+// toJWK 转换为 JWK 公钥
+func (k *Key) toJWK() JWK {
+	jwk := JWK{Kid: k.Kid, Use: "sig", Alg: k.method.Alg()}
+	switch pub := k.public.(type) {
+	case *rsa.PublicKey:
+		jwk.Kty = "RSA"
+		jwk.N = base64.RawURLEncoding.EncodeToString(pub.N.Bytes())
+		jwk.E = base64.RawURLEncoding.EncodeToString(big.NewInt(int64(pub.E)).Bytes())
+	case ed25519.PublicKey:
+		jwk.Kty = "OKP"
+		jwk.Crv = "Ed25519"
+		jwk.X = base64.RawURLEncoding.EncodeToString(pub)
+	}
+	return jwk
+}
+
+// JWKS 当前可用于验签的公钥集合（签名中与轮换后仍在保留期内的密钥）
+func JWKS() []JWK {
+	keys := []JWK{}
+	if current == nil {
+		return keys
+	}
+	current.mu.RLock()
+	defer current.mu.RUnlock()
+	for _, k := range current.keys {
+		keys = append(keys, k.toJWK())
+	}
+	sort.Slice(keys, func(i, j int) bool { return keys[i].Kid > keys[j].Kid })
+	return keys
+}
@@ -0,0 +1,285 @@
+package ldapauth
+
+import (
+	"adcms/internal/model"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/go-ldap/ldap/v3"
+)
+
+// LDAP / AD 目录认证：
+//   - 先用服务账号按 UserFilter 查找用户条目，再以条目 DN 和用户输入的密码绑定校验
+//   - 条目匹配 DisabledFilter 时视为目录中已停用
+//   - 分组（默认 memberOf）按 GroupRoles 映射为本地角色
+var (
+	ErrUserNotFound       = errors.New("目录中不存在该用户")
+	ErrInvalidCredentials = errors.New("用户名或密码错误")
+	ErrAccountDisabled    = errors.New("目录账号已停用")
+)
+
+const (
+	defaultUserFilter   = "(&(objectClass=person)(uid=%s))"
+	defaultUsernameAttr = "uid"
+	defaultEmailAttr    = "mail"
+	defaultNicknameAttr = "displayName"
+	defaultPhoneAttr    = "mobile"
+	defaultGroupAttr    = "memberOf"
+
+	dialTimeout    = 5 * time.Second
+	requestTimeout = 10 * time.Second
+)
+
+// Entry 目录中的用户条目
+type Entry struct {
+	DN       string   `json:"dn"`
+	Username string   `json:"username"`
+	Email    string   `json:"email"`
+	Nickname string   `json:"nickname"`
+	Phone    string   `json:"phone"`
+	Groups   []string `json:"groups"`
+	Disabled bool     `json:"disabled"`
+}
+
+func attr(name, def string) string {
+	if name == "" {
+		return def
+	}
+	return name
+}
+
+// Validate 校验连接地址与过滤器格式
+func Validate(cfg *model.LDAPConfig) error {
+	u, err := url.Parse(cfg.URL)
+	if err != nil || (u.Scheme != "ldap" && u.Scheme != "ldaps") || u.Host == "" {
+		return errors.New("目录地址必须是 ldap:// 或 ldaps:// 地址")
+	}
+	if _, err := ldap.ParseDN(cfg.BaseDN); err != nil || cfg.BaseDN == "" {
+		return errors.New("Base DN 格式错误")
+	}
+	if cfg.UserFilter != "" {
+		if strings.Count(cfg.UserFilter, "%s") != 1 {
+			return errors.New("用户过滤器必须包含且仅包含一个 %s")
+		}
+		if _, err := ldap.CompileFilter(fmt.Sprintf(cfg.UserFilter, "test")); err != nil {
+			return errors.New("用户过滤器格式错误")
+		}
+	}
+	if cfg.DisabledFilter != "" {
+		if _, err := ldap.CompileFilter(cfg.DisabledFilter); err != nil {
+			return errors.New("停用过滤器格式错误")
+		}
+	}
+	if _, err := GroupRoles(cfg); err != nil {
+		return errors.New("分组角色映射格式错误")
+	}
+	return nil
+}
+
+// dial 连接目录并以服务账号绑定（未配置服务账号时匿名查询）
+func dial(cfg *model.LDAPConfig) (*ldap.Conn, error) {
+	u, err := url.Parse(cfg.URL)
+	if err != nil {
+		return nil, err
+	}
+	tlsConfig := &tls.Config{
+		ServerName:         u.Hostname(),
+		InsecureSkipVerify: cfg.InsecureSkipVerify == 1,
+	}
+	conn, err := ldap.DialURL(cfg.URL,
+		ldap.DialWithDialer(&net.Dialer{Timeout: dialTimeout}),
+		ldap.DialWithTLSConfig(tlsConfig))
+	if err != nil {
+		return nil, fmt.Errorf("连接目录失败: %w", err)
+	}
+	conn.SetTimeout(requestTimeout)
+
+	if cfg.StartTLS == 1 && u.Scheme == "ldap" {
+		if err := conn.StartTLS(tlsConfig); err != nil {
+			conn.Close()
+			return nil, fmt.Errorf("StartTLS 失败: %w", err)
+		}
+	}
+	if cfg.BindDN != "" {
+		if err := conn.Bind(cfg.BindDN, cfg.BindPassword); err != nil {
+			conn.Close()
+			return nil, fmt.Errorf("服务账号绑定失败: %w", err)
+		}
+	}
+	return conn, nil
+}
+
+// Ping 测试连接与服务账号绑定
+func Ping(cfg *model.LDAPConfig) error {
+	conn, err := dial(cfg)
+	if err != nil {
+		return err
+	}
+	conn.Close()
+	return nil
+}
+
+// find 按用户名查找条目，匹配到多个时视为配置错误
+func find(conn *ldap.Conn, cfg *model.LDAPConfig, username string) (*Entry, error) {
+	attrs := []string{
+		attr(cfg.UsernameAttr, defaultUsernameAttr),
+		attr(cfg.EmailAttr, defaultEmailAttr),
+		attr(cfg.NicknameAttr, defaultNicknameAttr),
+		attr(cfg.PhoneAttr, defaultPhoneAttr),
+		attr(cfg.GroupAttr, defaultGroupAttr),
+		"cn",
+	}
+	filter := fmt.Sprintf(attr(cfg.UserFilter, defaultUserFilter), ldap.EscapeFilter(username))
+	result, err := conn.Search(ldap.NewSearchRequest(
+		cfg.BaseDN, ldap.ScopeWholeSubtree, ldap.NeverDerefAliases, 2, int(requestTimeout.Seconds()), false,
+		filter, attrs, nil,
+	))
+	if err != nil {
+		if ldap.IsErrorWithCode(err, ldap.LDAPResultNoSuchObject) {
+			return nil, ErrUserNotFound
+		}
+		return nil, fmt.Errorf("查询目录失败: %w", err)
+	}
+	switch len(result.Entries) {
+	case 0:
+		return nil, ErrUserNotFound
+	case 1:
+	default:
+		return nil, fmt.Errorf("用户名 %s 在目录中匹配到多个条目", username)
+	}
+
+	e := result.Entries[0]
+	entry := &Entry{
+		DN:       e.DN,
+		Username: e.GetEqualFoldAttributeValue(attr(cfg.UsernameAttr, defaultUsernameAttr)),
+		Email:    e.GetEqualFoldAttributeValue(attr(cfg.EmailAttr, defaultEmailAttr)),
+		Nickname: e.GetEqualFoldAttributeValue(attr(cfg.NicknameAttr, defaultNicknameAttr)),
+		Phone:    e.GetEqualFoldAttributeValue(attr(cfg.PhoneAttr, defaultPhoneAttr)),
+		Groups:   e.GetEqualFoldAttributeValues(attr(cfg.GroupAttr, defaultGroupAttr)),
+	}
+	if entry.Username == "" {
+		entry.Username = username
+	}
+	if entry.Nickname == "" {
+		entry.Nickname = e.GetEqualFoldAttributeValue("cn")
+	}
+
+	if cfg.DisabledFilter != "" {
+		result, err := conn.Search(ldap.NewSearchRequest(
+			entry.DN, ldap.ScopeBaseObject, ldap.NeverDerefAliases, 1, int(requestTimeout.Seconds()), false,
+			cfg.DisabledFilter, []string{"1.1"}, nil,
+		))
+		if err != nil {
+			return nil, fmt.Errorf("查询目录失败: %w", err)
+		}
+		entry.Disabled = len(result.Entries) > 0
+	}
+	return entry, nil
+}
+
+// Authenticate 校验用户名密码，成功时返回目录中的用户条目
+// 账号在目录中已停用时返回 ErrAccountDisabled，同时返回条目
+func Authenticate(cfg *model.LDAPConfig, username, password string) (*Entry, error) {
+	// 空密码会被目录当作匿名绑定而成功，必须拒绝
+	if username == "" || password == "" {
+		return nil, ErrInvalidCredentials
+	}
+	conn, err := dial(cfg)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	entry, err := find(conn, cfg, username)
+	if err != nil {
+		return nil, err
+	}
+	if entry.Disabled {
+		return entry, ErrAccountDisabled
+	}
+	if err := conn.Bind(entry.DN, password); err != nil {
+		if ldap.IsErrorWithCode(err, ldap.LDAPResultInvalidCredentials) {
+			return nil, ErrInvalidCredentials
+		}
+		return nil, fmt.Errorf("目录绑定失败: %w", err)
+	}
+	return entry, nil
+}
+
+// Lookup 按用户名批量查询条目（复用同一连接），目录中不存在的用户不出现在结果中
+func Lookup(cfg *model.LDAPConfig, usernames []string) (map[string]*Entry, error) {
+	conn, err := dial(cfg)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	entries := make(map[string]*Entry, len(usernames))
+	for _, username := range usernames {
+		entry, err := find(conn, cfg, username)
+		if errors.Is(err, ErrUserNotFound) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		entries[username] = entry
+	}
+	return entries, nil
+}
+
+// GroupRoles 解析分组角色映射
+func GroupRoles(cfg *model.LDAPConfig) ([]model.LDAPGroupRole, error) {
+	if strings.TrimSpace(cfg.GroupRoles) == "" {
+		return nil, nil
+	}
+	var mappings []model.LDAPGroupRole
+	if err := json.Unmarshal([]byte(cfg.GroupRoles), &mappings); err != nil {
+		return nil, err
+	}
+	return mappings, nil
+}
+
+// MapRoles 按分组映射出本地角色，未匹配到任何分组时使用默认角色
+// 映射中的分组可以写完整 DN，也可以只写 CN，均不区分大小写
+func MapRoles(cfg *model.LDAPConfig, groups []string) []uint {
+	mappings, _ := GroupRoles(cfg)
+	seen := make(map[uint]bool)
+	var roleIDs []uint
+	for _, g := range groups {
+		cn := groupCN(g)
+		for _, m := range mappings {
+			if m.RoleID == 0 || seen[m.RoleID] {
+				continue
+			}
+			if strings.EqualFold(m.Group, g) || (cn != "" && strings.EqualFold(m.Group, cn)) {
+				seen[m.RoleID] = true
+				roleIDs = append(roleIDs, m.RoleID)
+			}
+		}
+	}
+	if len(roleIDs) == 0 && cfg.DefaultRoleID > 0 {
+		roleIDs = append(roleIDs, cfg.DefaultRoleID)
+	}
+	return roleIDs
+}
+
+// groupCN 取分组 DN 第一段的 cn 值
+func groupCN(groupDN string) string {
+	dn, err := ldap.ParseDN(groupDN)
+	if err != nil || len(dn.RDNs) == 0 {
+		return ""
+	}
+	for _, a := range dn.RDNs[0].Attributes {
+		if strings.EqualFold(a.Type, "cn") {
+			return a.Value
+		}
+	}
+	return ""
+}
@@ -0,0 +1,282 @@
+package ldapauth
+
+import (
+	"adcms/internal/model"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	ber "github.com/go-asn1-ber/asn1-ber"
+	"github.com/go-ldap/ldap/v3"
+	"github.com/jimlambrt/gldap"
+)
+
+const (
+	baseDN      = "dc=example,dc=com"
+	serviceDN   = "cn=svc,dc=example,dc=com"
+	servicePass = "svc-secret"
+	devGroupDN  = "cn=dev,ou=groups,dc=example,dc=com"
+	opsGroupDN  = "cn=ops,ou=groups,dc=example,dc=com"
+)
+
+// testEntry 目录中的用户条目
+type testEntry struct {
+	dn       string
+	password string
+	attrs    map[string][]string
+}
+
+// testDirectory 进程内 LDAP 服务：服务账号与用户的简单绑定，按过滤器搜索用户条目
+type testDirectory struct {
+	entries []testEntry
+}
+
+func (d *testDirectory) bind(w *gldap.ResponseWriter, r *gldap.Request) {
+	resp := r.NewBindResponse(gldap.WithResponseCode(gldap.ResultInvalidCredentials))
+	defer w.Write(resp)
+
+	m, err := r.GetSimpleBindMessage()
+	if err != nil {
+		return
+	}
+	if m.UserName == serviceDN && string(m.Password) == servicePass {
+		resp.SetResultCode(gldap.ResultSuccess)
+		return
+	}
+	for _, e := range d.entries {
+		if strings.EqualFold(e.dn, m.UserName) && string(m.Password) == e.password {
+			resp.SetResultCode(gldap.ResultSuccess)
+			return
+		}
+	}
+}
+
+func (d *testDirectory) search(w *gldap.ResponseWriter, r *gldap.Request) {
+	resp := r.NewSearchDoneResponse(gldap.WithResponseCode(gldap.ResultOperationsError))
+	defer w.Write(resp)
+
+	m, err := r.GetSearchMessage()
+	if err != nil {
+		return
+	}
+	filter, err := ldap.CompileFilter(m.Filter)
+	if err != nil {
+		return
+	}
+	for _, e := range d.entries {
+		inScope := strings.HasSuffix(strings.ToLower(e.dn), strings.ToLower(m.BaseDN))
+		if m.Scope == gldap.BaseObject {
+			inScope = strings.EqualFold(e.dn, m.BaseDN)
+		}
+		if !inScope || !matchFilter(filter, e.attrs) {
+			continue
+		}
+		entry := r.NewSearchResponseEntry(e.dn)
+		for name, values := range e.attrs {
+			entry.AddAttribute(name, values)
+		}
+		w.Write(entry)
+	}
+	resp.SetResultCode(gldap.ResultSuccess)
+}
+
+// matchFilter 支持测试用到的 and/or/not/等值/存在性过滤器
+func matchFilter(f *ber.Packet, attrs map[string][]string) bool {
+	switch f.Tag {
+	case ldap.FilterAnd:
+		for _, child := range f.Children {
+			if !matchFilter(child, attrs) {
+				return false
+			}
+		}
+		return true
+	case ldap.FilterOr:
+		for _, child := range f.Children {
+			if matchFilter(child, attrs) {
+				return true
+			}
+		}
+		return false
+	case ldap.FilterNot:
+		return !matchFilter(f.Children[0], attrs)
+	case ldap.FilterEqualityMatch:
+		name := f.Children[0].Data.String()
+		value := f.Children[1].Data.String()
+		for k, values := range attrs {
+			if !strings.EqualFold(k, name) {
+				continue
+			}
+			for _, v := range values {
+				if strings.EqualFold(v, value) {
+					return true
+				}
+			}
+		}
+		return false
+	case ldap.FilterPresent:
+		for k := range attrs {
+			if strings.EqualFold(k, f.Data.String()) {
+				return true
+			}
+		}
+		return false
+	}
+	return false
+}
+
+func startDirectory(t *testing.T) (*testDirectory, *model.LDAPConfig) {
+	d := &testDirectory{entries: []testEntry{
+		{
+			dn:       "uid=zhangsan,ou=people,dc=example,dc=com",
+			password: "zs-pass",
+			attrs: map[string][]string{
+				"objectClass": {"person", "inetOrgPerson"},
+				"uid":         {"zhangsan"},
+				"cn":          {"Zhang San"},
+				"displayName": {"张三"},
+				"mail":        {"zhangsan@example.com"},
+				"mobile":      {"13800000000"},
+				"memberOf":    {devGroupDN, opsGroupDN},
+			},
+		},
+		{
+			dn:       "uid=lisi,ou=people,dc=example,dc=com",
+			password: "ls-pass",
+			attrs: map[string][]string{
+				"objectClass":  {"person"},
+				"uid":          {"lisi"},
+				"cn":           {"Li Si"},
+				"employeeType": {"disabled"},
+			},
+		},
+	}}
+
+	mux, err := gldap.NewMux()
+	if err != nil {
+		t.Fatal(err)
+	}
+	mux.Bind(d.bind)
+	mux.Search(d.search)
+	srv, err := gldap.NewServer()
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv.Router(mux)
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := l.Addr().String()
+	l.Close()
+	go srv.Run(addr)
+	t.Cleanup(func() { srv.Stop() })
+	for i := 0; i < 100 && !srv.Ready(); i++ {
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	cfg := &model.LDAPConfig{
+		URL:            "ldap://" + addr,
+		BindDN:         serviceDN,
+		BindPassword:   servicePass,
+		BaseDN:         baseDN,
+		DisabledFilter: "(employeeType=disabled)",
+		GroupRoles:     fmt.Sprintf(`[{"group":%q,"role_id":3},{"group":"OPS","role_id":4},{"group":"cn=qa,ou=groups,dc=example,dc=com","role_id":5}]`, devGroupDN),
+		DefaultRoleID:  2,
+		Status:         1,
+	}
+	return d, cfg
+}
+
+func TestAuthenticate(t *testing.T) {
+	_, cfg := startDirectory(t)
+
+	entry, err := Authenticate(cfg, "zhangsan", "zs-pass")
+	if err != nil {
+		t.Fatalf("Authenticate failed: %v", err)
+	}
+	if entry.Username != "zhangsan" || entry.Email != "zhangsan@example.com" || entry.Nickname != "张三" || entry.Phone != "13800000000" {
+		t.Fatalf("unexpected entry: %+v", entry)
+	}
+	if len(entry.Groups) != 2 {
+		t.Fatalf("expected 2 groups, got %v", entry.Groups)
+	}
+
+	if _, err := Authenticate(cfg, "zhangsan", "wrong"); !errors.Is(err, ErrInvalidCredentials) {
+		t.Fatalf("expected ErrInvalidCredentials, got %v", err)
+	}
+	// 空密码不能作为匿名绑定通过
+	if _, err := Authenticate(cfg, "zhangsan", ""); !errors.Is(err, ErrInvalidCredentials) {
+		t.Fatalf("expected ErrInvalidCredentials for empty password, got %v", err)
+	}
+	if _, err := Authenticate(cfg, "wangwu", "any"); !errors.Is(err, ErrUserNotFound) {
+		t.Fatalf("expected ErrUserNotFound, got %v", err)
+	}
+	// 过滤器注入：用户名中的特殊字符被转义
+	if _, err := Authenticate(cfg, "*", "zs-pass"); !errors.Is(err, ErrUserNotFound) {
+		t.Fatalf("expected ErrUserNotFound for wildcard username, got %v", err)
+	}
+	if _, err := Authenticate(cfg, "lisi", "ls-pass"); !errors.Is(err, ErrAccountDisabled) {
+		t.Fatalf("expected ErrAccountDisabled, got %v", err)
+	}
+}
+
+func TestServiceBindFailure(t *testing.T) {
+	_, cfg := startDirectory(t)
+	cfg.BindPassword = "wrong"
+
+	if err := Ping(cfg); err == nil {
+		t.Fatal("ping with wrong service password should fail")
+	}
+	if _, err := Authenticate(cfg, "zhangsan", "zs-pass"); err == nil || errors.Is(err, ErrUserNotFound) {
+		t.Fatalf("expected connection error, got %v", err)
+	}
+}
+
+func TestLookup(t *testing.T) {
+	_, cfg := startDirectory(t)
+
+	entries, err := Lookup(cfg, []string{"zhangsan", "lisi", "removed"})
+	if err != nil {
+		t.Fatalf("Lookup failed: %v", err)
+	}
+	if len(entries) != 2 {
+		t.Fatalf("expected 2 entries, got %d", len(entries))
+	}
+	if entries["zhangsan"].Disabled || !entries["lisi"].Disabled {
+		t.Fatalf("unexpected disabled state: %+v %+v", entries["zhangsan"], entries["lisi"])
+	}
+}
+
+func TestMapRoles(t *testing.T) {
+	_, cfg := startDirectory(t)
+
+	roles := MapRoles(cfg, []string{"CN=Dev,OU=Groups,DC=example,DC=com", opsGroupDN})
+	if len(roles) != 2 || roles[0] != 3 || roles[1] != 4 {
+		t.Fatalf("unexpected roles: %v", roles)
+	}
+	if roles := MapRoles(cfg, []string{"cn=sales,ou=groups,dc=example,dc=com"}); len(roles) != 1 || roles[0] != 2 {
+		t.Fatalf("expected default role, got %v", roles)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	cfg := &model.LDAPConfig{URL: "ldaps://ad.example.com", BaseDN: baseDN, UserFilter: "(sAMAccountName=%s)"}
+	if err := Validate(cfg); err != nil {
+		t.Fatalf("valid config rejected: %v", err)
+	}
+	for _, bad := range []model.LDAPConfig{
+		{URL: "http://ad.example.com", BaseDN: baseDN},
+		{URL: "ldap://ad.example.com", BaseDN: "not a dn"},
+		{URL: "ldap://ad.example.com", BaseDN: baseDN, UserFilter: "(uid=admin)"},
+		{URL: "ldap://ad.example.com", BaseDN: baseDN, DisabledFilter: "(broken"},
+		{URL: "ldap://ad.example.com", BaseDN: baseDN, GroupRoles: "{"},
+	} {
+		if err := Validate(&bad); err == nil {
+			t.Fatalf("invalid config accepted: %+v", bad)
+		}
+	}
+}
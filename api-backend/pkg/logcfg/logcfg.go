@@ -1,12 +1,16 @@
 package logcfg
 
 import (
+	"strconv"
 	"sync"
 	"time"
 
 	"gorm.io/gorm"
 )
 
+// DefaultRetentionDays 未配置时日志保留天数
+const DefaultRetentionDays = 30
+
 var (
 	db             *gorm.DB
 	logConfigCache = make(map[string]string)
@@ -22,18 +26,43 @@ func Init(database *gorm.DB) {
 
 // IsLogEnabled 检查某类日志是否启用，带1分钟内存缓存
 func IsLogEnabled(key string) bool {
+	val, ok := get(key)
+	if ok {
+		return val != "0"
+	}
+	return true
+}
+
+// RetentionDays 某类日志的保留天数（log_{logType}_retention_days），0=永久保留
+func RetentionDays(logType string) int {
+	val, ok := get("log_" + logType + "_retention_days")
+	if !ok {
+		return DefaultRetentionDays
+	}
+	days, err := strconv.Atoi(val)
+	if err != nil || days < 0 {
+		return DefaultRetentionDays
+	}
+	return days
+}
+
+// IsArchiveEnabled 清理前是否先将日志归档到本地归档目录（log_archive_enabled），默认关闭
+func IsArchiveEnabled() bool {
+	val, ok := get("log_archive_enabled")
+	return ok && val == "1"
+}
+
+// get 读取 log_ 开头的系统配置，带1分钟内存缓存
+func get(key string) (string, bool) {
 	if db == nil {
-		return true
+		return "", false
 	}
 
 	logConfigMu.RLock()
 	if time.Since(logConfigTime) < cacheTTL {
 		val, ok := logConfigCache[key]
 		logConfigMu.RUnlock()
-		if ok {
-			return val != "0"
-		}
-		return true
+		return val, ok
 	}
 	logConfigMu.RUnlock()
 
@@ -43,10 +72,7 @@ func IsLogEnabled(key string) bool {
 	// 双重检查
 	if time.Since(logConfigTime) < cacheTTL {
 		val, ok := logConfigCache[key]
-		if ok {
-			return val != "0"
-		}
-		return true
+		return val, ok
 	}
 
 	type kv struct {
@@ -54,7 +80,7 @@ func IsLogEnabled(key string) bool {
 		Value string
 	}
 	var configs []kv
-	db.Table("system_configs").Select("`key`, value").Where("`key` LIKE 'log_%_enabled'").Find(&configs)
+	db.Table("system_configs").Select("`key`, value").Where("`key` LIKE 'log\\_%'").Find(&configs)
 
 	newCache := make(map[string]string)
 	for _, c := range configs {
@@ -64,10 +90,7 @@ func IsLogEnabled(key string) bool {
 	logConfigTime = time.Now()
 
 	val, ok := newCache[key]
-	if ok {
-		return val != "0"
-	}
-	return true
+	return val, ok
 }
 
 // ClearCache 清除日志配置缓存（配置更新后调用）
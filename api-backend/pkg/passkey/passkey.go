@@ -0,0 +1,278 @@
+package passkey
+
+import (
+	"adcms/internal/config"
+	"adcms/internal/model"
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-webauthn/webauthn/protocol"
+	"github.com/go-webauthn/webauthn/webauthn"
+)
+
+// 通行密钥（WebAuthn）认证：
+//   - 登录用户注册通行密钥，凭证保存在 webauthn_credentials 表
+//   - 密码登录后可用通行密钥完成第二步验证（代替 TOTP）
+//   - 也可不输入用户名密码，直接用可发现凭证（passkey）登录，此时要求用户验证（指纹/PIN）
+//   - 仪式会话（challenge）保存在 Redis，一次性使用
+var (
+	ErrDisabled        = errors.New("未启用通行密钥登录")
+	ErrSessionNotFound = errors.New("验证已过期，请重新发起")
+	ErrCloned          = errors.New("通行密钥签名计数异常，可能已被复制")
+)
+
+var wa *webauthn.WebAuthn
+
+// Setup 按配置初始化，未配置 rp_id 时不启用
+func Setup() error {
+	cfg := config.GlobalConfig.WebAuthn
+	if cfg.RPID == "" {
+		wa = nil
+		return nil
+	}
+	return Init(cfg.RPID, cfg.RPName, cfg.Origins)
+}
+
+// Init 使用指定的站点信息初始化
+func Init(rpID, rpName string, origins []string) error {
+	if rpName == "" {
+		rpName = "ADCMS"
+	}
+	w, err := webauthn.New(&webauthn.Config{
+		RPID:          rpID,
+		RPDisplayName: rpName,
+		RPOrigins:     origins,
+		AuthenticatorSelection: protocol.AuthenticatorSelection{
+			ResidentKey:      protocol.ResidentKeyRequirementPreferred,
+			UserVerification: protocol.VerificationPreferred,
+		},
+	})
+	if err != nil {
+		return err
+	}
+	wa = w
+	return nil
+}
+
+// Enabled 是否启用了通行密钥登录
+func Enabled() bool {
+	return wa != nil
+}
+
+// User 实现 webauthn.User，关联用户已注册的凭证
+type User struct {
+	*model.User
+	Credentials []model.WebAuthnCredential
+}
+
+// UserHandle 用户在认证器中的标识（user.id），仅包含用户ID
+func UserHandle(userID uint) []byte {
+	return []byte(strconv.FormatUint(uint64(userID), 10))
+}
+
+func parseUserHandle(handle []byte) (uint, error) {
+	id, err := strconv.ParseUint(string(handle), 10, 64)
+	if err != nil || id == 0 {
+		return 0, errors.New("invalid user handle")
+	}
+	return uint(id), nil
+}
+
+func (u *User) WebAuthnID() []byte {
+	return UserHandle(u.ID)
+}
+
+func (u *User) WebAuthnName() string {
+	return u.Username
+}
+
+func (u *User) WebAuthnDisplayName() string {
+	if u.Nickname != "" {
+		return u.Nickname
+	}
+	return u.Username
+}
+
+func (u *User) WebAuthnCredentials() []webauthn.Credential {
+	creds := make([]webauthn.Credential, 0, len(u.Credentials))
+	for _, c := range u.Credentials {
+		cred, err := toCredential(c)
+		if err != nil {
+			continue
+		}
+		creds = append(creds, cred)
+	}
+	return creds
+}
+
+func toCredential(c model.WebAuthnCredential) (webauthn.Credential, error) {
+	id, err := base64.RawURLEncoding.DecodeString(c.CredentialID)
+	if err != nil {
+		return webauthn.Credential{}, err
+	}
+	var transports []protocol.AuthenticatorTransport
+	for _, t := range strings.Split(c.Transport, ",") {
+		if t != "" {
+			transports = append(transports, protocol.AuthenticatorTransport(t))
+		}
+	}
+	return webauthn.Credential{
+		ID:              id,
+		PublicKey:       c.PublicKey,
+		AttestationType: c.AttestationType,
+		Transport:       transports,
+		Flags: webauthn.CredentialFlags{
+			BackupEligible: c.BackupEligible,
+			BackupState:    c.BackupState,
+		},
+		Authenticator: webauthn.Authenticator{
+			AAGUID:    c.AAGUID,
+			SignCount: c.SignCount,
+		},
+	}, nil
+}
+
+func fromCredential(userID uint, cred *webauthn.Credential) *model.WebAuthnCredential {
+	transports := make([]string, len(cred.Transport))
+	for i, t := range cred.Transport {
+		transports[i] = string(t)
+	}
+	return &model.WebAuthnCredential{
+		UserID:          userID,
+		CredentialID:    base64.RawURLEncoding.EncodeToString(cred.ID),
+		PublicKey:       cred.PublicKey,
+		AttestationType: cred.AttestationType,
+		Transport:       strings.Join(transports, ","),
+		AAGUID:          cred.Authenticator.AAGUID,
+		SignCount:       cred.Authenticator.SignCount,
+		BackupEligible:  cred.Flags.BackupEligible,
+		BackupState:     cred.Flags.BackupState,
+	}
+}
+
+// BeginRegistration 为登录用户生成注册选项，已注册的凭证不会重复注册
+func BeginRegistration(u *User) (*protocol.CredentialCreation, error) {
+	if wa == nil {
+		return nil, ErrDisabled
+	}
+	creation, sess, err := wa.BeginRegistration(u,
+		webauthn.WithExclusions(webauthn.Credentials(u.WebAuthnCredentials()).CredentialDescriptors()))
+	if err != nil {
+		return nil, err
+	}
+	if err := store.save(registerKey(u.ID), sess); err != nil {
+		return nil, err
+	}
+	return creation, nil
+}
+
+// FinishRegistration 校验认证器返回的注册结果，返回待保存的凭证
+func FinishRegistration(u *User, body []byte) (*model.WebAuthnCredential, error) {
+	if wa == nil {
+		return nil, ErrDisabled
+	}
+	sess, err := store.take(registerKey(u.ID))
+	if err != nil {
+		return nil, err
+	}
+	parsed, err := protocol.ParseCredentialCreationResponseBytes(body)
+	if err != nil {
+		return nil, err
+	}
+	cred, err := wa.CreateCredential(u, *sess, parsed)
+	if err != nil {
+		return nil, err
+	}
+	return fromCredential(u.ID, cred), nil
+}
+
+// BeginLogin 生成登录选项：u 不为空时用于密码登录后的第二步验证，
+// 为空时发起无密码登录（可发现凭证），要求认证器完成用户验证
+func BeginLogin(u *User) (*protocol.CredentialAssertion, error) {
+	if wa == nil {
+		return nil, ErrDisabled
+	}
+	var (
+		assertion *protocol.CredentialAssertion
+		sess      *webauthn.SessionData
+		err       error
+	)
+	if u != nil {
+		assertion, sess, err = wa.BeginLogin(u)
+	} else {
+		assertion, sess, err = wa.BeginDiscoverableLogin(webauthn.WithUserVerification(protocol.VerificationRequired))
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := store.save(loginKey(sess.Challenge), sess); err != nil {
+		return nil, err
+	}
+	return assertion, nil
+}
+
+// FinishLogin 校验认证器返回的断言，返回登录用户及更新了签名计数的凭证
+// load 按用户ID加载用户及其凭证
+func FinishLogin(body []byte, load func(userID uint) (*User, error)) (*User, *model.WebAuthnCredential, error) {
+	if wa == nil {
+		return nil, nil, ErrDisabled
+	}
+	parsed, err := protocol.ParseCredentialRequestResponseBytes(body)
+	if err != nil {
+		return nil, nil, err
+	}
+	sess, err := store.take(loginKey(parsed.Response.CollectedClientData.Challenge))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var (
+		user *User
+		cred *webauthn.Credential
+	)
+	if len(sess.UserID) > 0 {
+		userID, err := parseUserHandle(sess.UserID)
+		if err != nil {
+			return nil, nil, err
+		}
+		if user, err = load(userID); err != nil {
+			return nil, nil, err
+		}
+		if cred, err = wa.ValidateLogin(user, *sess, parsed); err != nil {
+			return nil, nil, err
+		}
+	} else {
+		handler := func(rawID, userHandle []byte) (webauthn.User, error) {
+			userID, err := parseUserHandle(userHandle)
+			if err != nil {
+				return nil, err
+			}
+			user, err = load(userID)
+			return user, err
+		}
+		if _, cred, err = wa.ValidatePasskeyLogin(handler, *sess, parsed); err != nil {
+			return nil, nil, err
+		}
+	}
+	if cred.Authenticator.CloneWarning {
+		return nil, nil, ErrCloned
+	}
+
+	for i := range user.Credentials {
+		stored := &user.Credentials[i]
+		id, _ := base64.RawURLEncoding.DecodeString(stored.CredentialID)
+		if !bytes.Equal(id, cred.ID) {
+			continue
+		}
+		now := time.Now()
+		stored.SignCount = cred.Authenticator.SignCount
+		stored.BackupState = cred.Flags.BackupState
+		stored.LastUsedAt = &now
+		return user, stored, nil
+	}
+	return nil, nil, errors.New("credential not found")
+}
@@ -0,0 +1,251 @@
+package passkey
+
+import (
+	"adcms/internal/model"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/go-webauthn/webauthn/protocol"
+	"github.com/go-webauthn/webauthn/protocol/webauthncbor"
+	"github.com/go-webauthn/webauthn/protocol/webauthncose"
+	"github.com/go-webauthn/webauthn/webauthn"
+)
+
+const (
+	testRPID   = "example.com"
+	testOrigin = "https://example.com"
+)
+
+type memStore struct {
+	mu   sync.Mutex
+	data map[string]*webauthn.SessionData
+}
+
+func (s *memStore) save(key string, sess *webauthn.SessionData) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.data[key] = sess
+	return nil
+}
+
+func (s *memStore) take(key string) (*webauthn.SessionData, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	sess, ok := s.data[key]
+	if !ok {
+		return nil, ErrSessionNotFound
+	}
+	delete(s.data, key)
+	return sess, nil
+}
+
+// softAuthenticator 软件实现的 ES256 认证器，attestation 格式为 none
+type softAuthenticator struct {
+	key        *ecdsa.PrivateKey
+	credID     []byte
+	userHandle []byte
+	counter    uint32
+}
+
+func newSoftAuthenticator(t *testing.T) *softAuthenticator {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	credID := make([]byte, 16)
+	rand.Read(credID)
+	return &softAuthenticator{key: key, credID: credID}
+}
+
+func b64(b []byte) string {
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+func (a *softAuthenticator) authData(flags protocol.AuthenticatorFlags, attested []byte) []byte {
+	rpHash := sha256.Sum256([]byte(testRPID))
+	data := append([]byte{}, rpHash[:]...)
+	data = append(data, byte(flags))
+	data = binary.BigEndian.AppendUint32(data, a.counter)
+	return append(data, attested...)
+}
+
+func clientData(t *testing.T, typ, challenge string) []byte {
+	data, err := json.Marshal(map[string]string{"type": typ, "challenge": challenge, "origin": testOrigin})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+func (a *softAuthenticator) create(t *testing.T, creation *protocol.CredentialCreation) []byte {
+	pub, err := webauthncbor.Marshal(webauthncose.EC2PublicKeyData{
+		PublicKeyData: webauthncose.PublicKeyData{
+			KeyType:   int64(webauthncose.EllipticKey),
+			Algorithm: int64(webauthncose.AlgES256),
+		},
+		Curve:  1, // P-256
+		XCoord: a.key.X.FillBytes(make([]byte, 32)),
+		YCoord: a.key.Y.FillBytes(make([]byte, 32)),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	a.userHandle = creation.Response.User.ID.(protocol.URLEncodedBase64)
+
+	attested := make([]byte, 16) // AAGUID
+	attested = binary.BigEndian.AppendUint16(attested, uint16(len(a.credID)))
+	attested = append(attested, a.credID...)
+	attested = append(attested, pub...)
+	flags := protocol.FlagUserPresent | protocol.FlagUserVerified | protocol.FlagAttestedCredentialData
+
+	attObj, err := webauthncbor.Marshal(map[string]any{
+		"fmt":      "none",
+		"attStmt":  map[string]any{},
+		"authData": a.authData(flags, attested),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := json.Marshal(map[string]any{
+		"id":    b64(a.credID),
+		"rawId": b64(a.credID),
+		"type":  "public-key",
+		"response": map[string]string{
+			"clientDataJSON":    b64(clientData(t, "webauthn.create", creation.Response.Challenge.String())),
+			"attestationObject": b64(attObj),
+		},
+	})
+	return body
+}
+
+func (a *softAuthenticator) get(t *testing.T, assertion *protocol.CredentialAssertion, verified bool) []byte {
+	a.counter++
+	flags := protocol.FlagUserPresent
+	if verified {
+		flags |= protocol.FlagUserVerified
+	}
+	authData := a.authData(flags, nil)
+	cdj := clientData(t, "webauthn.get", assertion.Response.Challenge.String())
+	cdHash := sha256.Sum256(cdj)
+	digest := sha256.Sum256(append(append([]byte{}, authData...), cdHash[:]...))
+	sig, err := ecdsa.SignASN1(rand.Reader, a.key, digest[:])
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := json.Marshal(map[string]any{
+		"id":    b64(a.credID),
+		"rawId": b64(a.credID),
+		"type":  "public-key",
+		"response": map[string]string{
+			"clientDataJSON":    b64(cdj),
+			"authenticatorData": b64(authData),
+			"signature":         b64(sig),
+			"userHandle":        b64(a.userHandle),
+		},
+	})
+	return body
+}
+
+func setupTest(t *testing.T) (*User, *softAuthenticator) {
+	t.Helper()
+	store = &memStore{data: make(map[string]*webauthn.SessionData)}
+	if err := Init(testRPID, "ADCMS", []string{testOrigin}); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		wa = nil
+		store = redisStore{}
+	})
+
+	user := &User{User: &model.User{Username: "alice"}}
+	user.ID = 7
+	auth := newSoftAuthenticator(t)
+
+	creation, err := BeginRegistration(user)
+	if err != nil {
+		t.Fatalf("BeginRegistration failed: %v", err)
+	}
+	cred, err := FinishRegistration(user, auth.create(t, creation))
+	if err != nil {
+		t.Fatalf("FinishRegistration failed: %v", err)
+	}
+	if cred.UserID != user.ID || cred.CredentialID != b64(auth.credID) {
+		t.Fatalf("unexpected credential: %+v", cred)
+	}
+	user.Credentials = append(user.Credentials, *cred)
+	return user, auth
+}
+
+func TestSecondFactorLogin(t *testing.T) {
+	user, auth := setupTest(t)
+	load := func(id uint) (*User, error) {
+		if id != user.ID {
+			return nil, errors.New("not found")
+		}
+		return user, nil
+	}
+
+	assertion, err := BeginLogin(user)
+	if err != nil {
+		t.Fatalf("BeginLogin failed: %v", err)
+	}
+	body := auth.get(t, assertion, false)
+	got, cred, err := FinishLogin(body, load)
+	if err != nil {
+		t.Fatalf("FinishLogin failed: %v", err)
+	}
+	if got.ID != user.ID || cred.SignCount != 1 || cred.LastUsedAt == nil {
+		t.Fatalf("unexpected result: user=%d sign_count=%d", got.ID, cred.SignCount)
+	}
+
+	// 同一断言不能重放
+	if _, _, err := FinishLogin(body, load); !errors.Is(err, ErrSessionNotFound) {
+		t.Fatalf("replay should fail with ErrSessionNotFound, got %v", err)
+	}
+}
+
+func TestPasswordlessLogin(t *testing.T) {
+	user, auth := setupTest(t)
+	load := func(id uint) (*User, error) {
+		if id != user.ID {
+			return nil, errors.New("not found")
+		}
+		return user, nil
+	}
+
+	// 无密码登录要求用户验证
+	assertion, err := BeginLogin(nil)
+	if err != nil {
+		t.Fatalf("BeginLogin failed: %v", err)
+	}
+	if _, _, err := FinishLogin(auth.get(t, assertion, false), load); err == nil {
+		t.Fatal("passwordless login without user verification should fail")
+	}
+
+	assertion, err = BeginLogin(nil)
+	if err != nil {
+		t.Fatalf("BeginLogin failed: %v", err)
+	}
+	got, _, err := FinishLogin(auth.get(t, assertion, true), load)
+	if err != nil {
+		t.Fatalf("FinishLogin failed: %v", err)
+	}
+	if got.ID != user.ID {
+		t.Fatalf("expected user %d, got %d", user.ID, got.ID)
+	}
+}
+
+func TestDisabled(t *testing.T) {
+	wa = nil
+	if _, err := BeginLogin(nil); !errors.Is(err, ErrDisabled) {
+		t.Fatalf("expected ErrDisabled, got %v", err)
+	}
+}
@@ -0,0 +1,62 @@
+package passkey
+
+import (
+	"adcms/pkg/database"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/go-webauthn/webauthn/webauthn"
+)
+
+const (
+	sessionKeyPrefix = "webauthn:session:"
+	sessionTTL       = 5 * time.Minute
+)
+
+// sessionStore 保存注册/登录仪式的会话数据，take 取出后即删除，保证 challenge 只能使用一次
+type sessionStore interface {
+	save(key string, sess *webauthn.SessionData) error
+	take(key string) (*webauthn.SessionData, error)
+}
+
+var store sessionStore = redisStore{}
+
+func registerKey(userID uint) string {
+	return fmt.Sprintf("%sregister:%d", sessionKeyPrefix, userID)
+}
+
+func loginKey(challenge string) string {
+	sum := sha256.Sum256([]byte(challenge))
+	return sessionKeyPrefix + "login:" + hex.EncodeToString(sum[:])
+}
+
+type redisStore struct{}
+
+func (redisStore) save(key string, sess *webauthn.SessionData) error {
+	data, err := json.Marshal(sess)
+	if err != nil {
+		return err
+	}
+	return database.RDB.Set(context.Background(), key, data, sessionTTL).Err()
+}
+
+func (redisStore) take(key string) (*webauthn.SessionData, error) {
+	ctx := context.Background()
+	data, err := database.RDB.Get(ctx, key).Bytes()
+	if err != nil {
+		return nil, ErrSessionNotFound
+	}
+	// 并发提交同一 challenge 时只有删除成功的一方可以继续
+	if n, err := database.RDB.Del(ctx, key).Result(); err != nil || n == 0 {
+		return nil, ErrSessionNotFound
+	}
+	var sess webauthn.SessionData
+	if err := json.Unmarshal(data, &sess); err != nil {
+		return nil, err
+	}
+	return &sess, nil
+}
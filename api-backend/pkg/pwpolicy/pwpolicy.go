@@ -0,0 +1,235 @@
+package pwpolicy
+
+import (
+	"adcms/internal/model"
+	"adcms/pkg/database"
+	"adcms/pkg/utils"
+	"bufio"
+	"crypto/rand"
+	_ "embed"
+	"errors"
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// 密码策略：
+//   - 按租户保存在 system_configs（password_ 开头），租户未配置的项沿用全局（tenant_id=0），再沿用默认值
+//   - 校验长度、字符类别数、常见弱密码，以及不得与最近 N 次使用过的密码相同
+//   - 超过最长使用天数后，下次登录须先修改密码
+const (
+	KeyMinLength   = "password_min_length"
+	KeyMinClasses  = "password_min_classes"
+	KeyHistory     = "password_history"
+	KeyMaxAgeDays  = "password_max_age_days"
+	KeyBlockCommon = "password_block_common"
+
+	MaxHistory = 24 // 最多检查的历史密码数量
+)
+
+// Keys 策略涉及的全部配置项
+var Keys = []string{KeyMinLength, KeyMinClasses, KeyHistory, KeyMaxAgeDays, KeyBlockCommon}
+
+// Policy 密码策略
+type Policy struct {
+	MinLength   int  `json:"min_length"`   // 最短长度
+	MinClasses  int  `json:"min_classes"`  // 至少包含的字符类别数（大写、小写、数字、符号）
+	History     int  `json:"history"`      // 不得与最近 N 次密码相同，0=不限制
+	MaxAgeDays  int  `json:"max_age_days"` // 最长使用天数，0=永不过期
+	BlockCommon bool `json:"block_common"` // 禁止使用常见弱密码
+}
+
+// Default 未配置时的默认策略
+func Default() Policy {
+	return Policy{MinLength: 8, MinClasses: 2, History: 3, MaxAgeDays: 0, BlockCommon: true}
+}
+
+//go:embed common_passwords.txt
+var commonPasswordsFile string
+
+var commonPasswords = func() map[string]bool {
+	m := make(map[string]bool)
+	scanner := bufio.NewScanner(strings.NewReader(commonPasswordsFile))
+	for scanner.Scan() {
+		if line := strings.TrimSpace(scanner.Text()); line != "" && !strings.HasPrefix(line, "#") {
+			m[strings.ToLower(line)] = true
+		}
+	}
+	return m
+}()
+
+// IsCommon 是否为常见弱密码（不区分大小写）
+func IsCommon(password string) bool {
+	return commonPasswords[strings.ToLower(password)]
+}
+
+// Load 读取租户生效的密码策略
+func Load(tenantID uint) Policy {
+	var configs []model.SystemConfig
+	database.DB.Where("tenant_id IN ? AND `key` IN ?", []uint{0, tenantID}, Keys).
+		Order("tenant_id ASC").Find(&configs)
+
+	values := make(map[string]string)
+	for _, cfg := range configs {
+		values[cfg.Key] = cfg.Value // 租户配置在后，覆盖全局
+	}
+	return FromValues(values)
+}
+
+// FromValues 由配置项构造策略，缺失或非法的项使用默认值
+func FromValues(values map[string]string) Policy {
+	p := Default()
+	intValue := func(key string, min, max int, dst *int) {
+		if v, err := strconv.Atoi(values[key]); err == nil && v >= min && v <= max {
+			*dst = v
+		}
+	}
+	intValue(KeyMinLength, 6, 64, &p.MinLength)
+	intValue(KeyMinClasses, 1, 4, &p.MinClasses)
+	intValue(KeyHistory, 0, MaxHistory, &p.History)
+	intValue(KeyMaxAgeDays, 0, 3650, &p.MaxAgeDays)
+	if v, ok := values[KeyBlockCommon]; ok {
+		p.BlockCommon = v == "1"
+	}
+	return p
+}
+
+// Values 策略转为配置项
+func (p Policy) Values() map[string]string {
+	blockCommon := "0"
+	if p.BlockCommon {
+		blockCommon = "1"
+	}
+	return map[string]string{
+		KeyMinLength:   strconv.Itoa(p.MinLength),
+		KeyMinClasses:  strconv.Itoa(p.MinClasses),
+		KeyHistory:     strconv.Itoa(p.History),
+		KeyMaxAgeDays:  strconv.Itoa(p.MaxAgeDays),
+		KeyBlockCommon: blockCommon,
+	}
+}
+
+// Check 校验策略本身的取值范围
+func (p Policy) Check() error {
+	switch {
+	case p.MinLength < 6 || p.MinLength > 64:
+		return errors.New("最短长度须在6到64之间")
+	case p.MinClasses < 1 || p.MinClasses > 4:
+		return errors.New("字符类别数须在1到4之间")
+	case p.History < 0 || p.History > MaxHistory:
+		return fmt.Errorf("历史密码数须在0到%d之间", MaxHistory)
+	case p.MaxAgeDays < 0 || p.MaxAgeDays > 3650:
+		return errors.New("最长使用天数须在0到3650之间")
+	}
+	return nil
+}
+
+func classes(password string) int {
+	var upper, lower, digit, symbol bool
+	for _, r := range password {
+		switch {
+		case unicode.IsUpper(r):
+			upper = true
+		case unicode.IsLower(r):
+			lower = true
+		case unicode.IsDigit(r):
+			digit = true
+		default:
+			symbol = true
+		}
+	}
+	n := 0
+	for _, ok := range []bool{upper, lower, digit, symbol} {
+		if ok {
+			n++
+		}
+	}
+	return n
+}
+
+// Validate 校验密码强度，username 用于禁止密码与用户名相同
+func (p Policy) Validate(password, username string) error {
+	if len([]rune(password)) < p.MinLength {
+		return fmt.Errorf("密码长度不能少于%d位", p.MinLength)
+	}
+	if len(password) > 72 {
+		return errors.New("密码长度不能超过72个字节")
+	}
+	if classes(password) < p.MinClasses {
+		return fmt.Errorf("密码须至少包含大写字母、小写字母、数字、符号中的%d类", p.MinClasses)
+	}
+	if username != "" && strings.EqualFold(password, username) {
+		return errors.New("密码不能与用户名相同")
+	}
+	if p.BlockCommon && IsCommon(password) {
+		return errors.New("密码过于常见，请更换")
+	}
+	return nil
+}
+
+// Reused 密码是否与给定的历史哈希之一相同，History 为 0 时不检查
+func (p Policy) Reused(password string, hashes []string) bool {
+	if p.History == 0 {
+		return false
+	}
+	for _, h := range hashes {
+		if utils.ComparePassword(h, password) {
+			return true
+		}
+	}
+	return false
+}
+
+// Expired 密码自 changedAt 起是否已超过最长使用天数
+func (p Policy) Expired(changedAt time.Time) bool {
+	return p.MaxAgeDays > 0 && time.Since(changedAt) > time.Duration(p.MaxAgeDays)*24*time.Hour
+}
+
+const (
+	upperChars  = "ABCDEFGHJKLMNPQRSTUVWXYZ"
+	lowerChars  = "abcdefghijkmnpqrstuvwxyz"
+	digitChars  = "23456789"
+	symbolChars = "!@#$%^&*-_=+"
+)
+
+// Generate 生成满足策略的随机临时密码（包含全部四类字符）
+func (p Policy) Generate() (string, error) {
+	length := p.MinLength
+	if length < 12 {
+		length = 12
+	}
+	sets := []string{upperChars, lowerChars, digitChars, symbolChars}
+	all := strings.Join(sets, "")
+	buf := make([]byte, length)
+	for i := range buf {
+		set := all
+		if i < len(sets) {
+			set = sets[i]
+		}
+		c, err := randChar(set)
+		if err != nil {
+			return "", err
+		}
+		buf[i] = c
+	}
+	// 打乱顺序，避免固定位置的字符类别
+	for i := len(buf) - 1; i > 0; i-- {
+		j, err := rand.Int(rand.Reader, big.NewInt(int64(i+1)))
+		if err != nil {
+			return "", err
+		}
+		buf[i], buf[j.Int64()] = buf[j.Int64()], buf[i]
+	}
+	return string(buf), nil
+}
+
+func randChar(set string) (byte, error) {
+	n, err := rand.Int(rand.Reader, big.NewInt(int64(len(set))))
+	if err != nil {
+		return 0, err
+	}
+	return set[n.Int64()], nil
+}
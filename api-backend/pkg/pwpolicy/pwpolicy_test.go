@@ -0,0 +1,86 @@
+package pwpolicy
+
+import (
+	"adcms/pkg/utils"
+	"testing"
+	"time"
+)
+
+func TestValidate(t *testing.T) {
+	p := Default()
+	for _, tc := range []struct {
+		password string
+		ok       bool
+	}{
+		{"Ab1", false},         // 太短
+		{"abcdefghij", false},  // 只有一类字符
+		{"zhangsan1", false},   // 与用户名相同
+		{"Password123", false}, // 常见弱密码
+		{"PASSWORD123", false}, // 常见弱密码不区分大小写
+		{"Tq7#mVr2pL", true},
+		{"correct horse battery", true},
+	} {
+		err := p.Validate(tc.password, "ZhangSan1")
+		if (err == nil) != tc.ok {
+			t.Errorf("Validate(%q) = %v, want ok=%v", tc.password, err, tc.ok)
+		}
+	}
+
+	p.BlockCommon = false
+	if err := p.Validate("Password123", ""); err != nil {
+		t.Fatalf("common password should pass when blocking disabled: %v", err)
+	}
+}
+
+func TestFromValues(t *testing.T) {
+	p := FromValues(map[string]string{
+		KeyMinLength:   "12",
+		KeyMinClasses:  "9", // 越界，沿用默认值
+		KeyHistory:     "5",
+		KeyMaxAgeDays:  "90",
+		KeyBlockCommon: "0",
+	})
+	want := Policy{MinLength: 12, MinClasses: Default().MinClasses, History: 5, MaxAgeDays: 90, BlockCommon: false}
+	if p != want {
+		t.Fatalf("FromValues = %+v, want %+v", p, want)
+	}
+	if FromValues(p.Values()) != p {
+		t.Fatal("Values round trip mismatch")
+	}
+}
+
+func TestReusedAndExpired(t *testing.T) {
+	hash, _ := utils.HashPassword("Old#Pass1")
+	p := Default()
+	if !p.Reused("Old#Pass1", []string{hash}) || p.Reused("New#Pass1", []string{hash}) {
+		t.Fatal("Reused mismatch")
+	}
+	p.History = 0
+	if p.Reused("Old#Pass1", []string{hash}) {
+		t.Fatal("history check should be disabled")
+	}
+
+	if p.Expired(time.Now().AddDate(-1, 0, 0)) {
+		t.Fatal("max age 0 should never expire")
+	}
+	p.MaxAgeDays = 30
+	if !p.Expired(time.Now().AddDate(0, 0, -31)) || p.Expired(time.Now().AddDate(0, 0, -29)) {
+		t.Fatal("Expired mismatch")
+	}
+}
+
+func TestGenerate(t *testing.T) {
+	p := Policy{MinLength: 16, MinClasses: 4, BlockCommon: true}
+	for i := 0; i < 20; i++ {
+		pw, err := p.Generate()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(pw) != 16 {
+			t.Fatalf("generated length %d", len(pw))
+		}
+		if err := p.Validate(pw, ""); err != nil {
+			t.Fatalf("generated password %q rejected: %v", pw, err)
+		}
+	}
+}
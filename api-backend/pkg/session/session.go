@@ -0,0 +1,285 @@
+package session
+
+import (
+	"adcms/pkg/database"
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// 一次登录对应一个会话（refresh token 家族），会话ID 即 access token 的 jti：
+//   - session:{id}            会话信息（hash），TTL 为 refresh token 有效期，从登录时起算不续期
+//   - session:refresh:{hash}  refresh token 摘要 -> 会话ID，轮换后旧 token 保留至会话过期，用于识别重放
+//   - session:user:{userID}   用户的会话ID集合，用于列出/批量注销
+//
+// 每次刷新都会签发新的 refresh token 并作废旧的；已作废的 token 再次使用视为泄露，整个会话立即注销。
+const (
+	sessionKeyPrefix = "session:"
+	refreshKeyPrefix = "session:refresh:"
+	userKeyPrefix    = "session:user:"
+
+	// touchInterval 最后活跃时间的更新间隔，避免每个请求都写 Redis
+	touchInterval = 60
+)
+
+var (
+	ErrInvalid = errors.New("refresh token无效或已过期")
+	ErrRevoked = errors.New("会话已注销")
+	ErrReused  = errors.New("refresh token已被使用，会话已注销")
+)
+
+// Session 登录会话
+type Session struct {
+	ID        string    `json:"id"`
+	UserID    uint      `json:"user_id"`
+	TenantID  uint      `json:"tenant_id"`
+	IP        string    `json:"ip"`
+	UserAgent string    `json:"user_agent"`
+	CreatedAt time.Time `json:"created_at"`
+	LastSeen  time.Time `json:"last_seen"`
+	LastIP    string    `json:"last_ip"`
+	Current   bool      `json:"current"` // 是否为当前请求所在会话
+}
+
+// rotateScript 原子地校验并轮换 refresh token
+// 返回 {状态, 会话ID}：0=token不存在 1=会话已注销 2=重放（已注销会话） 3=轮换成功
+var rotateScript = redis.NewScript(`
+local sid = redis.call("GET", KEYS[1])
+if not sid then
+	return {0, ""}
+end
+local skey = ARGV[3] .. sid
+local current = redis.call("HGET", skey, "current")
+if not current then
+	return {1, sid}
+end
+if current ~= ARGV[1] then
+	redis.call("DEL", skey)
+	return {2, sid}
+end
+local ttl = redis.call("PTTL", skey)
+redis.call("HSET", skey, "current", ARGV[2])
+redis.call("SET", ARGV[4] .. ARGV[2], sid, "PX", ttl)
+return {3, sid}`)
+
+// touchScript 会话存在时按间隔更新最后活跃时间与IP，返回 1=会话有效 0=已注销
+var touchScript = redis.NewScript(`
+if redis.call("EXISTS", KEYS[1]) == 0 then
+	return 0
+end
+local last = tonumber(redis.call("HGET", KEYS[1], "last_seen") or "0")
+local now = tonumber(ARGV[1])
+if now - last >= tonumber(ARGV[3]) then
+	redis.call("HSET", KEYS[1], "last_seen", now, "last_ip", ARGV[2])
+end
+return 1`)
+
+// Create 创建会话并返回首个 refresh token
+func Create(s *Session, ttl time.Duration) (string, error) {
+	id, err := randomToken(16)
+	if err != nil {
+		return "", err
+	}
+	refresh, err := randomToken(32)
+	if err != nil {
+		return "", err
+	}
+	s.ID = id
+	s.CreatedAt = time.Now()
+	s.LastSeen = s.CreatedAt
+	s.LastIP = s.IP
+
+	ctx := context.Background()
+	hash := hashToken(refresh)
+	userKey := fmt.Sprintf("%s%d", userKeyPrefix, s.UserID)
+	pipe := database.RDB.TxPipeline()
+	pipe.HSet(ctx, sessionKeyPrefix+id, map[string]interface{}{
+		"user_id":    s.UserID,
+		"tenant_id":  s.TenantID,
+		"ip":         s.IP,
+		"user_agent": s.UserAgent,
+		"created_at": s.CreatedAt.Unix(),
+		"last_seen":  s.LastSeen.Unix(),
+		"last_ip":    s.LastIP,
+		"current":    hash,
+	})
+	pipe.Expire(ctx, sessionKeyPrefix+id, ttl)
+	pipe.Set(ctx, refreshKeyPrefix+hash, id, ttl)
+	pipe.SAdd(ctx, userKey, id)
+	pipe.Expire(ctx, userKey, ttl)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return "", err
+	}
+	return refresh, nil
+}
+
+// Rotate 使用 refresh token 换取新的 refresh token，返回所属会话
+// 旧 token 被重复使用时注销整个会话并返回 ErrReused
+func Rotate(refreshToken string) (*Session, string, error) {
+	if refreshToken == "" {
+		return nil, "", ErrInvalid
+	}
+	next, err := randomToken(32)
+	if err != nil {
+		return nil, "", err
+	}
+
+	ctx := context.Background()
+	hash := hashToken(refreshToken)
+	res, err := rotateScript.Run(ctx, database.RDB, []string{refreshKeyPrefix + hash},
+		hash, hashToken(next), sessionKeyPrefix, refreshKeyPrefix).Slice()
+	if err != nil {
+		return nil, "", err
+	}
+	status, _ := res[0].(int64)
+	sid, _ := res[1].(string)
+	switch status {
+	case 0:
+		return nil, "", ErrInvalid
+	case 1:
+		return nil, "", ErrRevoked
+	case 2:
+		return nil, "", ErrReused
+	}
+
+	s, err := Get(sid)
+	if err != nil {
+		return nil, "", err
+	}
+	return s, next, nil
+}
+
+// Get 获取会话信息
+func Get(id string) (*Session, error) {
+	values, err := database.RDB.HGetAll(context.Background(), sessionKeyPrefix+id).Result()
+	if err != nil {
+		return nil, err
+	}
+	if len(values) == 0 {
+		return nil, ErrRevoked
+	}
+	return parse(id, values), nil
+}
+
+// Touch 校验会话仍有效，并记录最后活跃时间与IP（用于校验 access token 所属会话未注销）
+func Touch(id, ip string) (bool, error) {
+	n, err := touchScript.Run(context.Background(), database.RDB, []string{sessionKeyPrefix + id},
+		time.Now().Unix(), ip, touchInterval).Int()
+	return n == 1, err
+}
+
+// List 列出用户的有效会话（按最后活跃时间倒序），顺带清理已过期的索引
+func List(userID uint) ([]*Session, error) {
+	ctx := context.Background()
+	userKey := fmt.Sprintf("%s%d", userKeyPrefix, userID)
+	ids, err := database.RDB.SMembers(ctx, userKey).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	pipe := database.RDB.Pipeline()
+	cmds := make([]*redis.StringStringMapCmd, len(ids))
+	for i, id := range ids {
+		cmds[i] = pipe.HGetAll(ctx, sessionKeyPrefix+id)
+	}
+	if len(ids) > 0 {
+		if _, err := pipe.Exec(ctx); err != nil {
+			return nil, err
+		}
+	}
+
+	sessions := make([]*Session, 0, len(ids))
+	var stale []interface{}
+	for i, cmd := range cmds {
+		if len(cmd.Val()) == 0 {
+			stale = append(stale, ids[i])
+			continue
+		}
+		sessions = append(sessions, parse(ids[i], cmd.Val()))
+	}
+	if len(stale) > 0 {
+		database.RDB.SRem(ctx, userKey, stale...)
+	}
+	sort.Slice(sessions, func(i, j int) bool { return sessions[i].LastSeen.After(sessions[j].LastSeen) })
+	return sessions, nil
+}
+
+// Revoke 注销会话，其下所有 refresh token 与 access token 立即失效
+func Revoke(id string) error {
+	if id == "" {
+		return nil
+	}
+	ctx := context.Background()
+	userID, err := database.RDB.HGet(ctx, sessionKeyPrefix+id, "user_id").Result()
+	if err != nil && err != redis.Nil {
+		return err
+	}
+	pipe := database.RDB.TxPipeline()
+	pipe.Del(ctx, sessionKeyPrefix+id)
+	if userID != "" {
+		pipe.SRem(ctx, userKeyPrefix+userID, id)
+	}
+	_, err = pipe.Exec(ctx)
+	return err
+}
+
+// RevokeUser 注销用户的全部会话（禁用、重置密码等场景），except 非空时保留该会话
+func RevokeUser(userID uint, except string) error {
+	ctx := context.Background()
+	userKey := fmt.Sprintf("%s%d", userKeyPrefix, userID)
+	ids, err := database.RDB.SMembers(ctx, userKey).Result()
+	if err != nil {
+		return err
+	}
+	pipe := database.RDB.TxPipeline()
+	for _, id := range ids {
+		if id == except {
+			continue
+		}
+		pipe.Del(ctx, sessionKeyPrefix+id)
+		pipe.SRem(ctx, userKey, id)
+	}
+	_, err = pipe.Exec(ctx)
+	return err
+}
+
+func parse(id string, values map[string]string) *Session {
+	userID, _ := strconv.ParseUint(values["user_id"], 10, 64)
+	tenantID, _ := strconv.ParseUint(values["tenant_id"], 10, 64)
+	createdAt, _ := strconv.ParseInt(values["created_at"], 10, 64)
+	lastSeen, _ := strconv.ParseInt(values["last_seen"], 10, 64)
+	return &Session{
+		ID:        id,
+		UserID:    uint(userID),
+		TenantID:  uint(tenantID),
+		IP:        values["ip"],
+		UserAgent: values["user_agent"],
+		CreatedAt: time.Unix(createdAt, 0),
+		LastSeen:  time.Unix(lastSeen, 0),
+		LastIP:    values["last_ip"],
+	}
+}
+
+// randomToken 生成 URL 安全的随机串
+func randomToken(n int) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("生成随机数失败: %w", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// hashToken Redis 中只保存 refresh token 的摘要
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,116 @@
+package session
+
+import (
+	"adcms/internal/model"
+	"adcms/pkg/database"
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// 用户 token 版本：签发时写入 access token，校验时与当前版本比对。
+// 改密、调整角色、禁用等操作递增版本，该用户已签发的 access token 全部立即失效，
+// 仍有效的会话可通过 refresh 换取携带新版本与最新身份信息的 token。
+//
+// 版本只增不减，缓存写入时只允许变大：递增后直接写入新版本，
+// 并发读取在递增前从数据库读到的旧版本不会覆盖缓存。
+const (
+	versionKeyPrefix = "token:version:"
+	versionCacheTTL  = 10 * time.Minute
+)
+
+// versionStore token 版本的持久化存储
+type versionStore interface {
+	Load(userID uint) (uint, error)
+	Increment(userID uint) (uint, error)
+}
+
+// versionCache token 版本缓存
+type versionCache interface {
+	Get(userID uint) (uint, bool)
+	// SetIfNewer 缓存不存在或比 v 旧时写入 v，返回写入后缓存中的版本
+	SetIfNewer(userID uint, v uint) (uint, error)
+}
+
+var (
+	tokenVersions     versionStore = dbVersionStore{}
+	tokenVersionCache versionCache = redisVersionCache{}
+)
+
+// Version 获取用户当前 token 版本（Redis 缓存，未命中时读取数据库）
+func Version(userID uint) (uint, error) {
+	if v, ok := tokenVersionCache.Get(userID); ok {
+		return v, nil
+	}
+	v, err := tokenVersions.Load(userID)
+	if err != nil {
+		return 0, err
+	}
+	// 读取期间版本可能已递增，以缓存中较新的版本为准
+	if cached, err := tokenVersionCache.SetIfNewer(userID, v); err == nil {
+		return cached, nil
+	}
+	return v, nil
+}
+
+// BumpVersion 递增用户 token 版本，使其已签发的 access token 全部失效
+func BumpVersion(userID uint) error {
+	v, err := tokenVersions.Increment(userID)
+	if err != nil {
+		return err
+	}
+	_, err = tokenVersionCache.SetIfNewer(userID, v)
+	return err
+}
+
+type dbVersionStore struct{}
+
+func (dbVersionStore) Load(userID uint) (uint, error) {
+	var user model.User
+	if err := database.DB.Select("id", "token_version").First(&user, userID).Error; err != nil {
+		return 0, err
+	}
+	return user.TokenVersion, nil
+}
+
+func (s dbVersionStore) Increment(userID uint) (uint, error) {
+	// 字段在模型上只允许创建时写入，避免 Save 整行更新时用旧值覆盖
+	err := database.DB.Exec("UPDATE users SET token_version = token_version + 1 WHERE id = ?", userID).Error
+	if err != nil {
+		return 0, err
+	}
+	return s.Load(userID)
+}
+
+// setIfNewerScript 缓存不存在或比 ARGV[1] 小时写入，返回写入后的值
+var setIfNewerScript = redis.NewScript(`
+local current = redis.call("GET", KEYS[1])
+if current and tonumber(current) >= tonumber(ARGV[1]) then
+	return tonumber(current)
+end
+redis.call("SET", KEYS[1], ARGV[1], "PX", ARGV[2])
+return tonumber(ARGV[1])`)
+
+type redisVersionCache struct{}
+
+func versionKey(userID uint) string {
+	return fmt.Sprintf("%s%d", versionKeyPrefix, userID)
+}
+
+func (redisVersionCache) Get(userID uint) (uint, bool) {
+	val, err := database.RDB.Get(context.Background(), versionKey(userID)).Result()
+	if err != nil {
+		return 0, false
+	}
+	v, err := strconv.ParseUint(val, 10, 64)
+	return uint(v), err == nil
+}
+
+func (redisVersionCache) SetIfNewer(userID uint, v uint) (uint, error) {
+	n, err := setIfNewerScript.Run(context.Background(), database.RDB, []string{versionKey(userID)},
+		v, versionCacheTTL.Milliseconds()).Int64()
+	return uint(n), err
+}
@@ -0,0 +1,98 @@
+package session
+
+import (
+	"sync"
+	"testing"
+)
+
+type memVersionStore struct {
+	mu sync.Mutex
+	v  map[uint]uint
+	// beforeLoadReturn 在 Load 读到值之后、返回之前调用，用于模拟并发递增
+	beforeLoadReturn func()
+}
+
+func (s *memVersionStore) Load(userID uint) (uint, error) {
+	s.mu.Lock()
+	v := s.v[userID]
+	s.mu.Unlock()
+	if s.beforeLoadReturn != nil {
+		hook := s.beforeLoadReturn
+		s.beforeLoadReturn = nil
+		hook()
+	}
+	return v, nil
+}
+
+func (s *memVersionStore) Increment(userID uint) (uint, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.v[userID]++
+	return s.v[userID], nil
+}
+
+type memVersionCache struct {
+	mu sync.Mutex
+	v  map[uint]uint
+}
+
+func (c *memVersionCache) Get(userID uint) (uint, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	v, ok := c.v[userID]
+	return v, ok
+}
+
+func (c *memVersionCache) SetIfNewer(userID uint, v uint) (uint, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if current, ok := c.v[userID]; ok && current >= v {
+		return current, nil
+	}
+	c.v[userID] = v
+	return v, nil
+}
+
+func useMemVersions(t *testing.T) (*memVersionStore, *memVersionCache) {
+	store := &memVersionStore{v: map[uint]uint{1: 5}}
+	cache := &memVersionCache{v: map[uint]uint{}}
+	oldStore, oldCache := tokenVersions, tokenVersionCache
+	tokenVersions, tokenVersionCache = store, cache
+	t.Cleanup(func() { tokenVersions, tokenVersionCache = oldStore, oldCache })
+	return store, cache
+}
+
+func TestBumpThenRead(t *testing.T) {
+	_, _ = useMemVersions(t)
+
+	if v, _ := Version(1); v != 5 {
+		t.Fatalf("Version() = %d, want 5", v)
+	}
+	if err := BumpVersion(1); err != nil {
+		t.Fatal(err)
+	}
+	// 缓存中已有旧版本时，递增后立即读到新版本
+	if v, _ := Version(1); v != 6 {
+		t.Fatalf("Version() after bump = %d, want 6", v)
+	}
+}
+
+func TestStaleReadDoesNotOverwriteBump(t *testing.T) {
+	store, cache := useMemVersions(t)
+
+	// 读取方在递增前从数据库读到旧版本，递增完成后才写缓存
+	store.beforeLoadReturn = func() {
+		if err := BumpVersion(1); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if v, _ := Version(1); v != 6 {
+		t.Errorf("Version() during bump = %d, want 6", v)
+	}
+	if v, _ := cache.Get(1); v != 6 {
+		t.Errorf("cached version = %d, want 6", v)
+	}
+	if v, _ := Version(1); v != 6 {
+		t.Errorf("Version() after bump = %d, want 6", v)
+	}
+}
@@ -0,0 +1,230 @@
+package sso
+
+import (
+	"adcms/internal/model"
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+type memStore struct {
+	mu   sync.Mutex
+	data map[string]*loginState
+}
+
+func (s *memStore) save(state string, st *loginState) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.data[state] = st
+	return nil
+}
+
+func (s *memStore) take(state string) (*loginState, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	st, ok := s.data[state]
+	if !ok {
+		return nil, ErrStateNotFound
+	}
+	delete(s.data, state)
+	return st, nil
+}
+
+// mockIdP 本地模拟的 OIDC 身份提供方：discovery、JWKS、授权码换 token（校验 PKCE）
+type mockIdP struct {
+	*httptest.Server
+	key      *rsa.PrivateKey
+	clientID string
+	claims   map[string]interface{}
+
+	mu    sync.Mutex
+	codes map[string]authRequest
+}
+
+type authRequest struct {
+	challenge string
+	nonce     string
+}
+
+func newMockIdP(t *testing.T) *mockIdP {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	idp := &mockIdP{key: key, clientID: "adcms", codes: make(map[string]authRequest)}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"issuer":                                idp.URL,
+			"authorization_endpoint":                idp.URL + "/authorize",
+			"token_endpoint":                        idp.URL + "/token",
+			"jwks_uri":                              idp.URL + "/jwks",
+			"id_token_signing_alg_values_supported": []string{"RS256"},
+		})
+	})
+	mux.HandleFunc("/jwks", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"keys": []map[string]string{{
+				"kty": "RSA",
+				"alg": "RS256",
+				"use": "sig",
+				"kid": "test",
+				"n":   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+				"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+			}},
+		})
+	})
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		idp.mu.Lock()
+		req, ok := idp.codes[r.Form.Get("code")]
+		delete(idp.codes, r.Form.Get("code"))
+		idp.mu.Unlock()
+		sum := sha256.Sum256([]byte(r.Form.Get("code_verifier")))
+		if !ok || base64.RawURLEncoding.EncodeToString(sum[:]) != req.challenge {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"error": "invalid_grant"})
+			return
+		}
+		claims := jwt.MapClaims{
+			"iss":   idp.URL,
+			"aud":   idp.clientID,
+			"exp":   time.Now().Add(time.Minute).Unix(),
+			"iat":   time.Now().Unix(),
+			"nonce": req.nonce,
+		}
+		for k, v := range idp.claims {
+			claims[k] = v
+		}
+		token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+		token.Header["kid"] = "test"
+		idToken, _ := token.SignedString(key)
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"access_token": "access",
+			"token_type":   "Bearer",
+			"expires_in":   60,
+			"id_token":     idToken,
+		})
+	})
+	idp.Server = httptest.NewServer(mux)
+	t.Cleanup(idp.Close)
+	return idp
+}
+
+// authorize 模拟用户在 IdP 登录并同意授权，返回授权码和 state
+func (idp *mockIdP) authorize(t *testing.T, authURL string) (code, state string) {
+	u, err := url.Parse(authURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	q := u.Query()
+	if q.Get("client_id") != idp.clientID || q.Get("code_challenge_method") != "S256" {
+		t.Fatalf("unexpected authorize request: %s", authURL)
+	}
+	code = "code-" + q.Get("state")
+	idp.mu.Lock()
+	idp.codes[code] = authRequest{challenge: q.Get("code_challenge"), nonce: q.Get("nonce")}
+	idp.mu.Unlock()
+	return code, q.Get("state")
+}
+
+func setupTest(t *testing.T) (*mockIdP, *model.OIDCProvider, func(uint) (*model.OIDCProvider, error)) {
+	store = &memStore{data: make(map[string]*loginState)}
+	t.Cleanup(func() { store = redisStore{} })
+
+	idp := newMockIdP(t)
+	idp.claims = map[string]interface{}{
+		"sub":        "u-1001",
+		"upn":        "zhangsan",
+		"email":      "zhangsan@corp.example.com",
+		"name":       "张三",
+		"department": "研发部",
+	}
+	p := &model.OIDCProvider{
+		Issuer:        idp.URL,
+		ClientID:      idp.clientID,
+		RedirectURL:   "https://admin.example.com/auth/oidc/callback",
+		UsernameClaim: "upn",
+		Status:        1,
+	}
+	p.ID = 3
+	p.TenantID = 8
+	load := func(id uint) (*model.OIDCProvider, error) {
+		if id != p.ID {
+			return nil, errors.New("not found")
+		}
+		return p, nil
+	}
+	return idp, p, load
+}
+
+func TestLoginFlow(t *testing.T) {
+	idp, p, load := setupTest(t)
+
+	authURL, issued, err := AuthCodeURL(p)
+	if err != nil {
+		t.Fatalf("AuthCodeURL failed: %v", err)
+	}
+	code, state := idp.authorize(t, authURL)
+	if state != issued {
+		t.Fatalf("returned state %q does not match authorize url state %q", issued, state)
+	}
+
+	got, ident, err := Exchange(context.Background(), state, code, load)
+	if err != nil {
+		t.Fatalf("Exchange failed: %v", err)
+	}
+	if got.TenantID != 8 {
+		t.Fatalf("expected tenant 8, got %d", got.TenantID)
+	}
+	want := Identity{Subject: "u-1001", Username: "zhangsan", Email: "zhangsan@corp.example.com", Nickname: "张三"}
+	if *ident != want {
+		t.Fatalf("unexpected identity: %+v", ident)
+	}
+
+	// state 只能使用一次
+	if _, _, err := Exchange(context.Background(), state, code, load); !errors.Is(err, ErrStateNotFound) {
+		t.Fatalf("expected ErrStateNotFound, got %v", err)
+	}
+}
+
+func TestExchangeRejectsWrongVerifier(t *testing.T) {
+	idp, p, load := setupTest(t)
+
+	authURL, _, err := AuthCodeURL(p)
+	if err != nil {
+		t.Fatalf("AuthCodeURL failed: %v", err)
+	}
+	code, state := idp.authorize(t, authURL)
+	store.(*memStore).data[state].Verifier = "tampered"
+
+	if _, _, err := Exchange(context.Background(), state, code, load); err == nil {
+		t.Fatal("exchange with wrong PKCE verifier should fail")
+	}
+}
+
+func TestMapClaimsFallback(t *testing.T) {
+	p := &model.OIDCProvider{}
+	ident := MapClaims(p, "sub-1", map[string]interface{}{"email": "lisi@example.com"})
+	if ident.Username != "lisi" {
+		t.Fatalf("expected username from email, got %q", ident.Username)
+	}
+	ident = MapClaims(p, "sub-2", map[string]interface{}{})
+	if ident.Username != "sub-2" {
+		t.Fatalf("expected username from subject, got %q", ident.Username)
+	}
+}
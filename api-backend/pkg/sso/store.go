@@ -0,0 +1,58 @@
+package sso
+
+import (
+	"adcms/pkg/database"
+	"context"
+	"encoding/json"
+	"time"
+)
+
+const (
+	stateKeyPrefix = "oidc:state:"
+	StateTTL       = 10 * time.Minute // 发起登录到回调的有效期
+)
+
+// loginState 发起登录时保存的一次性数据
+type loginState struct {
+	ProviderID uint   `json:"provider_id"`
+	Verifier   string `json:"verifier"` // PKCE code_verifier
+	Nonce      string `json:"nonce"`
+}
+
+// stateStore take 取出后即删除，保证 state 只能使用一次
+type stateStore interface {
+	save(state string, st *loginState) error
+	take(state string) (*loginState, error)
+}
+
+var store stateStore = redisStore{}
+
+type redisStore struct{}
+
+func (redisStore) save(state string, st *loginState) error {
+	data, err := json.Marshal(st)
+	if err != nil {
+		return err
+	}
+	return database.RDB.Set(context.Background(), stateKeyPrefix+state, data, StateTTL).Err()
+}
+
+func (redisStore) take(state string) (*loginState, error) {
+	if state == "" {
+		return nil, ErrStateNotFound
+	}
+	ctx := context.Background()
+	key := stateKeyPrefix + state
+	data, err := database.RDB.Get(ctx, key).Bytes()
+	if err != nil {
+		return nil, ErrStateNotFound
+	}
+	if n, err := database.RDB.Del(ctx, key).Result(); err != nil || n == 0 {
+		return nil, ErrStateNotFound
+	}
+	var st loginState
+	if err := json.Unmarshal(data, &st); err != nil {
+		return nil, err
+	}
+	return &st, nil
+}
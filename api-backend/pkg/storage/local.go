@@ -108,6 +108,36 @@ func (s *LocalStorage) Delete(path string) error {
 	return nil
 }
 
+func (s *LocalStorage) List(prefix string) ([]ObjectInfo, error) {
+	root := filepath.Join(s.BasePath, prefix)
+	var objects []ObjectInfo
+	err := filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) {
+				return nil
+			}
+			return err
+		}
+		if info.IsDir() {
+			return nil
+		}
+		relPath, err := filepath.Rel(s.BasePath, path)
+		if err != nil {
+			return err
+		}
+		objects = append(objects, ObjectInfo{
+			Path:    relPath,
+			Size:    info.Size(),
+			ModTime: info.ModTime(),
+		})
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("列出文件失败: %w", err)
+	}
+	return objects, nil
+}
+
 func (s *LocalStorage) GetURL(path string) string {
 	return s.BaseURL + "/" + strings.ReplaceAll(path, "\\", "/")
 }
@@ -139,6 +139,27 @@ func (s *MinIOStorage) Delete(path string) error {
 	return nil
 }
 
+func (s *MinIOStorage) List(prefix string) ([]ObjectInfo, error) {
+	prefix = strings.Trim(strings.ReplaceAll(prefix, "\\", "/"), "/")
+	if prefix != "" {
+		prefix += "/"
+	}
+
+	ctx := context.Background()
+	var objects []ObjectInfo
+	for obj := range s.client.ListObjects(ctx, s.bucket, minio.ListObjectsOptions{Prefix: prefix, Recursive: true}) {
+		if obj.Err != nil {
+			return nil, fmt.Errorf("列出MinIO文件失败: %w", obj.Err)
+		}
+		objects = append(objects, ObjectInfo{
+			Path:    obj.Key,
+			Size:    obj.Size,
+			ModTime: obj.LastModified,
+		})
+	}
+	return objects, nil
+}
+
 func (s *MinIOStorage) GetURL(path string) string {
 	return s.baseURL + "/" + strings.ReplaceAll(path, "\\", "/")
 }
@@ -1,10 +1,15 @@
 package storage
 
 import (
+	"fmt"
 	"io"
 	"mime/multipart"
+	"time"
 )
 
+// TempDir 临时文件区（预览、分片等尚未确认的上传），由定时任务按存活时间清理
+const TempDir = "tmp"
+
 // FileInfo 上传后的文件信息
 type FileInfo struct {
 	Name     string `json:"name"`      // 原始文件名
@@ -14,6 +19,13 @@ type FileInfo struct {
 	MimeType string `json:"mime_type"` // MIME类型
 }
 
+// ObjectInfo 已存储文件的信息
+type ObjectInfo struct {
+	Path    string    `json:"path"`     // 存储路径（相对）
+	Size    int64     `json:"size"`     // 文件大小
+	ModTime time.Time `json:"mod_time"` // 最后修改时间
+}
+
 // Storage 文件存储接口
 type Storage interface {
 	// Upload 上传文件
@@ -22,6 +34,8 @@ type Storage interface {
 	UploadReader(reader io.Reader, filename string, dir string) (*FileInfo, error)
 	// Delete 删除文件
 	Delete(path string) error
+	// List 递归列出目录（前缀）下的所有文件，目录不存在时返回空列表
+	List(prefix string) ([]ObjectInfo, error)
 	// GetURL 获取文件访问URL
 	GetURL(path string) string
 	// Type 存储类型标识
@@ -35,3 +49,27 @@ var Default Storage
 func Init(s Storage) {
 	Default = s
 }
+
+// UploadTemp 上传到临时文件区，按租户分目录
+func UploadTemp(file *multipart.FileHeader, tenantID uint) (*FileInfo, error) {
+	return Default.Upload(file, fmt.Sprintf("%s/%d", TempDir, tenantID))
+}
+
+// DeleteOlderThan 删除前缀下修改时间早于 before 的文件，返回删除数量
+func DeleteOlderThan(s Storage, prefix string, before time.Time) (int, error) {
+	objects, err := s.List(prefix)
+	if err != nil {
+		return 0, err
+	}
+	deleted := 0
+	for _, obj := range objects {
+		if !obj.ModTime.Before(before) {
+			continue
+		}
+		if err := s.Delete(obj.Path); err != nil {
+			return deleted, err
+		}
+		deleted++
+	}
+	return deleted, nil
+}
@@ -1,8 +1,11 @@
 package storage
 
 import (
+	"os"
+	"path/filepath"
 	"strings"
 	"testing"
+	"time"
 )
 
 func TestLocalStorage_UploadReader(t *testing.T) {
@@ -46,3 +49,60 @@ func TestDefaultStorage(t *testing.T) {
 		t.Fatal("Default should not be nil after Init()")
 	}
 }
+
+func TestLocalStorage_List(t *testing.T) {
+	tmpDir := t.TempDir()
+	s := NewLocalStorage(tmpDir, "/uploads")
+
+	if objects, err := s.List(TempDir); err != nil || len(objects) != 0 {
+		t.Fatalf("List on missing dir = %v, %v; want empty", objects, err)
+	}
+
+	for _, name := range []string{"a.txt", "b.txt"} {
+		if _, err := s.UploadReader(strings.NewReader(name), name, TempDir); err != nil {
+			t.Fatalf("UploadReader failed: %v", err)
+		}
+	}
+	if _, err := s.UploadReader(strings.NewReader("keep"), "keep.txt", "1"); err != nil {
+		t.Fatalf("UploadReader failed: %v", err)
+	}
+
+	objects, err := s.List(TempDir)
+	if err != nil {
+		t.Fatalf("List failed: %v", err)
+	}
+	if len(objects) != 2 {
+		t.Fatalf("List returned %d objects, want 2", len(objects))
+	}
+	for _, obj := range objects {
+		if !strings.HasPrefix(filepath.ToSlash(obj.Path), TempDir+"/") || obj.ModTime.IsZero() {
+			t.Errorf("unexpected object %+v", obj)
+		}
+	}
+}
+
+func TestDeleteOlderThan(t *testing.T) {
+	tmpDir := t.TempDir()
+	s := NewLocalStorage(tmpDir, "/uploads")
+
+	oldInfo, _ := s.UploadReader(strings.NewReader("old"), "old.txt", TempDir)
+	newInfo, _ := s.UploadReader(strings.NewReader("new"), "new.txt", TempDir)
+	past := time.Now().Add(-48 * time.Hour)
+	if err := os.Chtimes(filepath.Join(tmpDir, oldInfo.Path), past, past); err != nil {
+		t.Fatal(err)
+	}
+
+	deleted, err := DeleteOlderThan(s, TempDir, time.Now().Add(-24*time.Hour))
+	if err != nil {
+		t.Fatalf("DeleteOlderThan failed: %v", err)
+	}
+	if deleted != 1 {
+		t.Errorf("deleted = %d, want 1", deleted)
+	}
+	if _, err := os.Stat(filepath.Join(tmpDir, oldInfo.Path)); !os.IsNotExist(err) {
+		t.Error("old file should be deleted")
+	}
+	if _, err := os.Stat(filepath.Join(tmpDir, newInfo.Path)); err != nil {
+		t.Error("new file should be kept")
+	}
+}
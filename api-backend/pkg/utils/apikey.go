@@ -0,0 +1,43 @@
+package utils
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"strings"
+)
+
+// API Key 格式：adk_{prefix}_{secret}
+// prefix 为 8 位十六进制，明文保存用于查找和展示；secret 只保存 SHA-256
+const apiKeyScheme = "adk"
+
+// GenerateAPIKey 生成 API Key，返回完整密钥、前缀和 secret 哈希
+func GenerateAPIKey() (key, prefix, secretHash string, err error) {
+	p := make([]byte, 4)
+	if _, err = rand.Read(p); err != nil {
+		return
+	}
+	s := make([]byte, 32)
+	if _, err = rand.Read(s); err != nil {
+		return
+	}
+	prefix = hex.EncodeToString(p)
+	secret := base64.RawURLEncoding.EncodeToString(s)
+	return apiKeyScheme + "_" + prefix + "_" + secret, prefix, HashAPIKeySecret(secret), nil
+}
+
+// ParseAPIKey 拆分 API Key 的前缀与 secret
+func ParseAPIKey(key string) (prefix, secret string, ok bool) {
+	parts := strings.SplitN(strings.TrimSpace(key), "_", 3)
+	if len(parts) != 3 || parts[0] != apiKeyScheme || len(parts[1]) != 8 || parts[2] == "" {
+		return "", "", false
+	}
+	return parts[1], parts[2], true
+}
+
+// HashAPIKeySecret 计算 secret 哈希
+func HashAPIKeySecret(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
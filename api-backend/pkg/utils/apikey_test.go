@@ -0,0 +1,23 @@
+package utils
+
+import "testing"
+
+func TestAPIKeyRoundTrip(t *testing.T) {
+	key, prefix, hash, err := GenerateAPIKey()
+	if err != nil {
+		t.Fatalf("GenerateAPIKey failed: %v", err)
+	}
+	p, secret, ok := ParseAPIKey(key)
+	if !ok || p != prefix {
+		t.Fatalf("ParseAPIKey(%q) = %q, %v", key, p, ok)
+	}
+	if HashAPIKeySecret(secret) != hash {
+		t.Fatal("secret hash mismatch")
+	}
+
+	for _, bad := range []string{"", "adk_1234", "xyz_12345678_secret", "adk_123_secret", "adk_12345678_"} {
+		if _, _, ok := ParseAPIKey(bad); ok {
+			t.Fatalf("ParseAPIKey accepted %q", bad)
+		}
+	}
+}
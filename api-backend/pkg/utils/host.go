@@ -0,0 +1,30 @@
+package utils
+
+import (
+	"errors"
+	"net"
+)
+
+var (
+	ErrHostUnresolvable = errors.New("地址无法解析")
+	ErrHostInternal     = errors.New("不允许使用内网、本机或链路本地地址")
+)
+
+// CheckPublicHost 校验主机名（或 IP）解析出的全部地址均为公网地址，
+// 用于服务端会主动连接的、由租户配置的地址，防止借此探测内网
+func CheckPublicHost(host string) error {
+	ips := []net.IP{net.ParseIP(host)}
+	if ips[0] == nil {
+		var err error
+		if ips, err = net.LookupIP(host); err != nil || len(ips) == 0 {
+			return ErrHostUnresolvable
+		}
+	}
+	for _, ip := range ips {
+		if ip.IsLoopback() || ip.IsPrivate() || ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() ||
+			ip.IsUnspecified() || ip.IsMulticast() {
+			return ErrHostInternal
+		}
+	}
+	return nil
+}
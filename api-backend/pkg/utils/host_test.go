@@ -0,0 +1,19 @@
+package utils
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestCheckPublicHost(t *testing.T) {
+	for _, host := range []string{"127.0.0.1", "10.1.2.3", "192.168.0.1", "169.254.169.254", "0.0.0.0", "::1", "fe80::1", "fd00::1"} {
+		if err := CheckPublicHost(host); !errors.Is(err, ErrHostInternal) {
+			t.Errorf("CheckPublicHost(%q) = %v, want ErrHostInternal", host, err)
+		}
+	}
+	for _, host := range []string{"8.8.8.8", "2001:4860:4860::8888"} {
+		if err := CheckPublicHost(host); err != nil {
+			t.Errorf("CheckPublicHost(%q) = %v, want nil", host, err)
+		}
+	}
+}
@@ -8,43 +8,167 @@ import (
 	"github.com/golang-jwt/jwt/v5"
 )
 
+// token 用途（typ），同一密钥签发的各类 token 只能用于各自的场景
+const (
+	TokenTypeAccess         = "access"     // 访问接口
+	TokenTypeMFA            = "mfa"        // 密码验证通过后等待两步验证
+	TokenTypePasswordChange = "pwd_change" // 密码过期，仅可用于修改密码
+)
+
 type Claims struct {
+	Type     string `json:"typ"`
 	UserID   uint   `json:"user_id"`
 	TenantID uint   `json:"tenant_id"`
 	Username string `json:"username"`
 	IsAdmin  int8   `json:"is_admin"`
+	Version  uint   `json:"ver"` // 用户 token 版本，与当前版本不一致时失效
+	// AuthTime 最近一次验证密码或两步验证的时间（Unix 秒），敏感操作据此判断是否需要重新验证
+	AuthTime int64 `json:"auth_time,omitempty"`
+	// ImpersonatorID 超管代登录时为超管用户ID，ImpersonatorSession 为超管自己的会话ID，结束代登录时据此返回
+	ImpersonatorID      uint   `json:"impersonator_id,omitempty"`
+	ImpersonatorSession string `json:"impersonator_sid,omitempty"`
+	// RegisteredClaims.ID（jti）为所属登录会话ID，会话注销后 token 立即失效；为空表示不绑定会话
 	jwt.RegisteredClaims
 }
 
 type TempClaims struct {
+	Type       string `json:"typ"`
 	UserID     uint   `json:"user_id"`
 	TenantID   uint   `json:"tenant_id"`
 	Username   string `json:"username"`
 	RequireOTP bool   `json:"require_otp"`
+	// PasswordChange 密码过期时签发，仅可用于修改密码
+	PasswordChange bool `json:"password_change,omitempty"`
 	jwt.RegisteredClaims
 }
 
-func GenerateToken(userID, tenantID uint, username string, isAdmin int8) (string, error) {
+// ImpersonationTTL 代登录 token 的最长有效期，到期后不可刷新，需重新发起
+const ImpersonationTTL = 30 * time.Minute
+
+// AccessTokenTTL access token 有效期：配置了 access_minutes 时使用短期 token，否则沿用 expire_hours
+func AccessTokenTTL() time.Duration {
 	cfg := config.GlobalConfig.JWT
+	if cfg.AccessMinutes > 0 {
+		return time.Duration(cfg.AccessMinutes) * time.Minute
+	}
+	return time.Duration(cfg.ExpireHours) * time.Hour
+}
+
+// RefreshTokenTTL refresh token（登录会话）有效期
+func RefreshTokenTTL() time.Duration {
+	cfg := config.GlobalConfig.JWT
+	if cfg.RefreshHours > 0 {
+		return time.Duration(cfg.RefreshHours) * time.Hour
+	}
+	return 7 * 24 * time.Hour
+}
+
+// KeyProvider 提供 JWT 签名与验签密钥，默认使用 jwt.secret 的 HS256
+type KeyProvider interface {
+	// SigningKey 返回当前签名密钥，kid 非空时写入 token 头
+	SigningKey() (kid string, method jwt.SigningMethod, key interface{}, err error)
+	// VerificationKey 按 token 头中的 kid 与算法返回验签密钥
+	VerificationKey(kid, alg string) (interface{}, error)
+}
+
+var keyProvider KeyProvider = HMACKeyProvider{}
+
+// SetKeyProvider 替换签名密钥来源（如 RS256/EdDSA 轮换密钥）
+func SetKeyProvider(p KeyProvider) {
+	keyProvider = p
+}
+
+// HMACKeyProvider 使用配置中的 jwt.secret 签名（HS256）
+type HMACKeyProvider struct{}
+
+func (HMACKeyProvider) SigningKey() (string, jwt.SigningMethod, interface{}, error) {
+	return "", jwt.SigningMethodHS256, []byte(config.GlobalConfig.JWT.Secret), nil
+}
+
+func (HMACKeyProvider) VerificationKey(kid, alg string) (interface{}, error) {
+	if kid != "" || alg != jwt.SigningMethodHS256.Alg() {
+		return nil, errors.New("unexpected signing method")
+	}
+	return []byte(config.GlobalConfig.JWT.Secret), nil
+}
+
+func signToken(claims jwt.Claims) (string, error) {
+	kid, method, key, err := keyProvider.SigningKey()
+	if err != nil {
+		return "", err
+	}
+	token := jwt.NewWithClaims(method, claims)
+	if kid != "" {
+		token.Header["kid"] = kid
+	}
+	return token.SignedString(key)
+}
+
+func verificationKey(token *jwt.Token) (interface{}, error) {
+	kid, _ := token.Header["kid"].(string)
+	return keyProvider.VerificationKey(kid, token.Method.Alg())
+}
+
+func GenerateToken(userID, tenantID uint, username string, isAdmin int8, version uint, sessionID string) (string, error) {
+	return GenerateAuthToken(userID, tenantID, username, isAdmin, version, sessionID, time.Time{})
+}
+
+// GenerateAuthToken 签发带身份验证时间的 access token，authTime 为零值时不写入
+func GenerateAuthToken(userID, tenantID uint, username string, isAdmin int8, version uint, sessionID string, authTime time.Time) (string, error) {
 	claims := Claims{
+		Type:     TokenTypeAccess,
 		UserID:   userID,
 		TenantID: tenantID,
 		Username: username,
 		IsAdmin:  isAdmin,
+		Version:  version,
 		RegisteredClaims: jwt.RegisteredClaims{
-			ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Duration(cfg.ExpireHours) * time.Hour)),
+			ID:        sessionID,
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(AccessTokenTTL())),
 			IssuedAt:  jwt.NewNumericDate(time.Now()),
 			NotBefore: jwt.NewNumericDate(time.Now()),
 		},
 	}
+	if !authTime.IsZero() {
+		claims.AuthTime = authTime.Unix()
+	}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	return token.SignedString([]byte(cfg.Secret))
+	return signToken(claims)
+}
+
+// GenerateImpersonationToken 签发代登录 token，有效期不超过 ImpersonationTTL 与 access token 有效期
+func GenerateImpersonationToken(userID, tenantID uint, username string, isAdmin int8, version uint, sessionID string, impersonatorID uint, impersonatorSession string) (string, error) {
+	claims := Claims{
+		Type:                TokenTypeAccess,
+		UserID:              userID,
+		TenantID:            tenantID,
+		Username:            username,
+		IsAdmin:             isAdmin,
+		Version:             version,
+		ImpersonatorID:      impersonatorID,
+		ImpersonatorSession: impersonatorSession,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        sessionID,
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(ImpersonationLifetime())),
+			IssuedAt:  jwt.NewNumericDate(time.Now()),
+			NotBefore: jwt.NewNumericDate(time.Now()),
+		},
+	}
+
+	return signToken(claims)
+}
+
+// ImpersonationLifetime 代登录会话实际有效期
+func ImpersonationLifetime() time.Duration {
+	if ttl := AccessTokenTTL(); ttl < ImpersonationTTL {
+		return ttl
+	}
+	return ImpersonationTTL
 }
 
 func GenerateTempToken(userID, tenantID uint, username string) (string, error) {
-	cfg := config.GlobalConfig.JWT
 	claims := TempClaims{
+		Type:       TokenTypeMFA,
 		UserID:     userID,
 		TenantID:   tenantID,
 		Username:   username,
@@ -56,22 +180,35 @@ func GenerateTempToken(userID, tenantID uint, username string) (string, error) {
 		},
 	}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	return token.SignedString([]byte(cfg.Secret))
+	return signToken(claims)
 }
 
-func ParseToken(tokenString string) (*Claims, error) {
-	cfg := config.GlobalConfig.JWT
+// GeneratePasswordChangeToken 登录时密码已过期，签发仅用于修改密码的临时 token
+func GeneratePasswordChangeToken(userID, tenantID uint, username string) (string, error) {
+	claims := TempClaims{
+		Type:           TokenTypePasswordChange,
+		UserID:         userID,
+		TenantID:       tenantID,
+		Username:       username,
+		PasswordChange: true,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(10 * time.Minute)),
+			IssuedAt:  jwt.NewNumericDate(time.Now()),
+			NotBefore: jwt.NewNumericDate(time.Now()),
+		},
+	}
 
-	token, err := jwt.ParseWithClaims(tokenString, &Claims{}, func(token *jwt.Token) (interface{}, error) {
-		return []byte(cfg.Secret), nil
-	})
+	return signToken(claims)
+}
+
+func ParseToken(tokenString string) (*Claims, error) {
+	token, err := jwt.ParseWithClaims(tokenString, &Claims{}, verificationKey)
 
 	if err != nil {
 		return nil, err
 	}
 
-	if claims, ok := token.Claims.(*Claims); ok && token.Valid {
+	if claims, ok := token.Claims.(*Claims); ok && token.Valid && claims.Type == TokenTypeAccess {
 		return claims, nil
 	}
 
@@ -79,28 +216,19 @@ func ParseToken(tokenString string) (*Claims, error) {
 }
 
 func ParseTempToken(tokenString string) (*TempClaims, error) {
-	cfg := config.GlobalConfig.JWT
-
-	token, err := jwt.ParseWithClaims(tokenString, &TempClaims{}, func(token *jwt.Token) (interface{}, error) {
-		return []byte(cfg.Secret), nil
-	})
+	token, err := jwt.ParseWithClaims(tokenString, &TempClaims{}, verificationKey)
 
 	if err != nil {
 		return nil, err
 	}
 
 	if claims, ok := token.Claims.(*TempClaims); ok && token.Valid {
-		return claims, nil
+		// 标记与用途不符的 token 一律拒绝，避免两步验证 token 被当作修改密码 token 使用（反之亦然）
+		if (claims.Type == TokenTypeMFA && claims.RequireOTP && !claims.PasswordChange) ||
+			(claims.Type == TokenTypePasswordChange && claims.PasswordChange && !claims.RequireOTP) {
+			return claims, nil
+		}
 	}
 
 	return nil, errors.New("invalid token")
 }
-
-func RefreshToken(tokenString string) (string, error) {
-	claims, err := ParseToken(tokenString)
-	if err != nil {
-		return "", err
-	}
-
-	return GenerateToken(claims.UserID, claims.TenantID, claims.Username, claims.IsAdmin)
-}
@@ -0,0 +1,33 @@
+package utils
+
+import (
+	"adcms/internal/config"
+	"testing"
+)
+
+func TestTokenTypes(t *testing.T) {
+	config.GlobalConfig = &config.Config{JWT: config.JWTConfig{Secret: "test-secret", ExpireHours: 1}}
+
+	access, _ := GenerateToken(1, 1, "admin", 1, 0, "sid")
+	mfa, _ := GenerateTempToken(1, 1, "admin")
+	pwdChange, _ := GeneratePasswordChangeToken(1, 1, "admin")
+
+	if _, err := ParseToken(access); err != nil {
+		t.Fatalf("ParseToken(access) error = %v", err)
+	}
+	for name, token := range map[string]string{"mfa": mfa, "pwd_change": pwdChange} {
+		if _, err := ParseToken(token); err == nil {
+			t.Errorf("ParseToken(%s) should reject temp token", name)
+		}
+	}
+
+	if claims, err := ParseTempToken(mfa); err != nil || !claims.RequireOTP {
+		t.Errorf("ParseTempToken(mfa) = %+v, %v", claims, err)
+	}
+	if claims, err := ParseTempToken(pwdChange); err != nil || !claims.PasswordChange {
+		t.Errorf("ParseTempToken(pwd_change) = %+v, %v", claims, err)
+	}
+	if _, err := ParseTempToken(access); err == nil {
+		t.Error("ParseTempToken should reject access token")
+	}
+}
@@ -2,8 +2,13 @@ package utils
 
 import (
 	"bytes"
+	"crypto/rand"
+	"crypto/sha256"
 	"encoding/base64"
+	"encoding/hex"
 	"image/png"
+	"math/big"
+	"strings"
 
 	"github.com/pquerna/otp"
 	"github.com/pquerna/otp/totp"
@@ -33,3 +38,52 @@ func GenerateTOTPQRCode(key *otp.Key) (string, error) {
 func ValidateTOTPCode(secret, code string) bool {
 	return totp.Validate(code, secret)
 }
+
+// RecoveryCodeCount 每次生成的恢复码数量
+const RecoveryCodeCount = 10
+
+// 恢复码字符集，去掉易混淆的 0/1/i/l/o
+const recoveryCodeAlphabet = "23456789abcdefghjkmnpqrstuvwxyz"
+
+// GenerateRecoveryCodes 生成 n 个一次性恢复码，格式 xxxxx-xxxxx
+func GenerateRecoveryCodes(n int) ([]string, error) {
+	codes := make([]string, n)
+	max := big.NewInt(int64(len(recoveryCodeAlphabet)))
+	for i := range codes {
+		var sb strings.Builder
+		for j := 0; j < 10; j++ {
+			if j == 5 {
+				sb.WriteByte('-')
+			}
+			idx, err := rand.Int(rand.Reader, max)
+			if err != nil {
+				return nil, err
+			}
+			sb.WriteByte(recoveryCodeAlphabet[idx.Int64()])
+		}
+		codes[i] = sb.String()
+	}
+	return codes, nil
+}
+
+// HashRecoveryCode 计算恢复码哈希，忽略大小写、空格和连字符
+func HashRecoveryCode(code string) string {
+	code = strings.ToLower(code)
+	code = strings.NewReplacer("-", "", " ", "").Replace(code)
+	sum := sha256.Sum256([]byte(code))
+	return hex.EncodeToString(sum[:])
+}
+
+// IsRecoveryCode 判断输入是否为恢复码格式（区别于6位数字的动态验证码）
+func IsRecoveryCode(code string) bool {
+	code = strings.NewReplacer("-", "", " ", "").Replace(strings.ToLower(code))
+	if len(code) != 10 {
+		return false
+	}
+	for _, r := range code {
+		if !strings.ContainsRune(recoveryCodeAlphabet, r) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,38 @@
+package utils
+
+import "testing"
+
+func TestGenerateRecoveryCodes(t *testing.T) {
+	codes, err := GenerateRecoveryCodes(RecoveryCodeCount)
+	if err != nil {
+		t.Fatalf("GenerateRecoveryCodes failed: %v", err)
+	}
+	if len(codes) != RecoveryCodeCount {
+		t.Fatalf("expected %d codes, got %d", RecoveryCodeCount, len(codes))
+	}
+	seen := make(map[string]bool)
+	for _, code := range codes {
+		if len(code) != 11 || code[5] != '-' {
+			t.Fatalf("unexpected code format: %q", code)
+		}
+		if !IsRecoveryCode(code) {
+			t.Fatalf("IsRecoveryCode(%q) should be true", code)
+		}
+		if seen[code] {
+			t.Fatalf("duplicate code: %q", code)
+		}
+		seen[code] = true
+	}
+}
+
+func TestHashRecoveryCode(t *testing.T) {
+	if HashRecoveryCode("abcde-fghjk") != HashRecoveryCode(" ABCDE FGHJK ") {
+		t.Fatal("HashRecoveryCode should ignore case, spaces and dashes")
+	}
+	if HashRecoveryCode("abcde-fghjk") == HashRecoveryCode("abcde-fghjm") {
+		t.Fatal("different codes should have different hashes")
+	}
+	if IsRecoveryCode("123456") {
+		t.Fatal("TOTP code should not be treated as recovery code")
+	}
+}
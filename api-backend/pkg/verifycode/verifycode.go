@@ -0,0 +1,134 @@
+package verifycode
+
+import (
+	"adcms/pkg/database"
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"math/big"
+	"time"
+)
+
+// 短信 / 邮件一次性验证码：
+//   - 同一接收方（手机号、邮箱）发送冷却期内只能发一次，每天有发送上限，与用途无关
+//   - 验证码按用途与接收方保存，错误次数达到上限后作废，需重新获取
+//   - 校验通过且业务操作完成后调用 Consume 作废
+var (
+	ErrCooldown        = errors.New("发送过于频繁，请1分钟后再试")
+	ErrDailyLimit      = errors.New("今日发送次数已达上限，请明天再试")
+	ErrInvalid         = errors.New("验证码错误或已过期")
+	ErrTooManyAttempts = errors.New("验证码错误次数过多，请重新获取")
+)
+
+// Purpose 验证码用途，不同用途的验证码互不通用
+type Purpose string
+
+const (
+	PurposeBindPhone     Purpose = "bind_phone"
+	PurposeLogin         Purpose = "login"
+	PurposeResetPassword Purpose = "reset_password"
+)
+
+const (
+	CodeLength  = 6
+	TTL         = 5 * time.Minute
+	Cooldown    = time.Minute
+	DailyLimit  = 10
+	MaxAttempts = 5
+)
+
+func codeKey(purpose Purpose, target string) string {
+	return fmt.Sprintf("vcode:%s:%s", purpose, target)
+}
+
+func attemptsKey(purpose Purpose, target string) string {
+	return fmt.Sprintf("vcode:attempts:%s:%s", purpose, target)
+}
+
+func cooldownKey(target string) string {
+	return "vcode:cooldown:" + target
+}
+
+func dailyKey(target string) string {
+	return fmt.Sprintf("vcode:daily:%s:%s", target, time.Now().Format("20060102"))
+}
+
+// Issue 为接收方生成验证码，调用方负责发送
+func Issue(purpose Purpose, target string) (string, error) {
+	ctx := context.Background()
+
+	// SetNX 同时完成冷却检查与占位，避免并发请求绕过冷却
+	ok, err := database.RDB.SetNX(ctx, cooldownKey(target), "1", Cooldown).Result()
+	if err != nil {
+		return "", err
+	}
+	if !ok {
+		return "", ErrCooldown
+	}
+
+	daily := dailyKey(target)
+	count, err := database.RDB.Incr(ctx, daily).Result()
+	if err != nil {
+		return "", err
+	}
+	if count == 1 {
+		database.RDB.Expire(ctx, daily, 24*time.Hour)
+	}
+	if count > DailyLimit {
+		return "", ErrDailyLimit
+	}
+
+	code, err := generate()
+	if err != nil {
+		return "", err
+	}
+	if err := database.RDB.Set(ctx, codeKey(purpose, target), code, TTL).Err(); err != nil {
+		return "", err
+	}
+	database.RDB.Del(ctx, attemptsKey(purpose, target))
+	return code, nil
+}
+
+// Check 校验验证码，错误次数达到上限后验证码作废
+func Check(purpose Purpose, target, code string) error {
+	ctx := context.Background()
+	key := codeKey(purpose, target)
+	stored, err := database.RDB.Get(ctx, key).Result()
+	if err != nil {
+		return ErrInvalid
+	}
+
+	if subtle.ConstantTimeCompare([]byte(stored), []byte(code)) == 1 {
+		return nil
+	}
+
+	attempts := attemptsKey(purpose, target)
+	n, _ := database.RDB.Incr(ctx, attempts).Result()
+	if n == 1 {
+		database.RDB.Expire(ctx, attempts, TTL)
+	}
+	if n >= MaxAttempts {
+		database.RDB.Del(ctx, key, attempts)
+		return ErrTooManyAttempts
+	}
+	return ErrInvalid
+}
+
+// Consume 作废验证码，校验通过后、变更任何状态之前调用，返回 false 时不得继续操作
+// 返回验证码是否仍存在，并发请求中只有一个能成功作废
+func Consume(purpose Purpose, target string) bool {
+	ctx := context.Background()
+	n, err := database.RDB.Del(ctx, codeKey(purpose, target)).Result()
+	database.RDB.Del(ctx, attemptsKey(purpose, target))
+	return err == nil && n > 0
+}
+
+func generate() (string, error) {
+	n, err := rand.Int(rand.Reader, big.NewInt(1000000))
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%0*d", CodeLength, n.Int64()), nil
+}